@@ -0,0 +1,109 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const LogTimestampFormat = "2006-01-02T15:04:05.00-0700"
+
+// AppLogsClient gives logs of an app, recent ones are reported when an app fails to stage or start
+// and streamed ones permit to follow staging and startup
+type AppLogsClient interface {
+	RecentLogs(appGUID string) (string, error)
+	// StreamLogs calls write with logs of the app emitted from now until stop is called
+	StreamLogs(appGUID string, write func(logs string)) (stop func(), err error)
+}
+
+// FormatAppLogMessage formats a log message like cf cli does, a line is written for each line of a multiline message
+func FormatAppLogMessage(timestamp time.Time, sourceType, sourceInstance string, isErr bool, message string) string {
+	typeMessage := "OUT"
+	if isErr {
+		typeMessage = "ERR"
+	}
+	header := fmt.Sprintf("%s [%s/%s] %s ",
+		timestamp.In(time.Local).Format(LogTimestampFormat),
+		sourceType,
+		sourceInstance,
+		typeMessage,
+	)
+	logs := ""
+	for _, line := range strings.Split(message, "\n") {
+		logs += fmt.Sprintf("\t%s%s\n", header, strings.TrimRight(line, "\r\n"))
+	}
+	return logs
+}
+
+// StreamAppLogs follows logs of an app into terraform log, and into the file <app name>_<app guid>.log of logsDir when set.
+// Streaming is best effort: failures are logged as warnings and never fail the deployment.
+// The returned func stops streaming, it must be called when the step (e.g. staging) is done.
+func StreamAppLogs(client AppLogsClient, logsDir, appGUID, appName, step string) func() {
+	sink := &appLogsSink{
+		appName: appName,
+		step:    step,
+	}
+	if logsDir != "" {
+		err := os.MkdirAll(logsDir, 0755)
+		if err == nil {
+			sink.file, err = os.OpenFile(
+				filepath.Join(logsDir, fmt.Sprintf("%s_%s.log", appName, appGUID)),
+				os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644,
+			)
+		}
+		if err != nil {
+			log.Printf("[WARN] app %s logs can't be written in directory %s: %s", appName, logsDir, err.Error())
+		}
+	}
+
+	stop, err := client.StreamLogs(appGUID, sink.write)
+	if err != nil {
+		log.Printf("[WARN] app %s %s logs can't be streamed: %s", appName, step, err.Error())
+		sink.close()
+		return func() {}
+	}
+	return func() {
+		stop()
+		sink.close()
+	}
+}
+
+// appLogsSink writes streamed logs, logs given after close are dropped as some clients
+// may still deliver messages while their stream is shutting down
+type appLogsSink struct {
+	mu      sync.Mutex
+	closed  bool
+	file    *os.File
+	appName string
+	step    string
+}
+
+func (s *appLogsSink) write(logs string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || logs == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+		log.Printf("[INFO] app %s %s: %s", s.appName, s.step, strings.TrimLeft(line, "\t"))
+	}
+	if s.file != nil {
+		_, err := s.file.WriteString(logs)
+		if err != nil {
+			log.Printf("[WARN] app %s logs can't be written in %s: %s", s.appName, s.file.Name(), err.Error())
+		}
+	}
+}
+
+func (s *appLogsSink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.file != nil {
+		s.file.Close()
+	}
+}
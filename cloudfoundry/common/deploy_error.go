@@ -0,0 +1,30 @@
+package common
+
+import (
+	"fmt"
+)
+
+// DeployErrorDetail is a diagnostic detail of a failed deployment (e.g. app logs, crashed instances)
+type DeployErrorDetail struct {
+	Summary string
+	Detail  string
+}
+
+// DeployError is an app deployment failure with details explaining it, details are meant to be reported
+// as separate diagnostics
+type DeployError struct {
+	Err     error
+	Details []DeployErrorDetail
+}
+
+func (e *DeployError) Error() string {
+	msg := e.Err.Error()
+	for _, detail := range e.Details {
+		msg += fmt.Sprintf("\n\n%s:\n%s", detail.Summary, detail.Detail)
+	}
+	return msg
+}
+
+func (e *DeployError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,328 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateJSONSchema validates a decoded json document against a decoded json schema and returns an error
+// for each field not matching it. Only keywords commonly used in service broker plan schemas are checked
+// (type, enum, const, properties, patternProperties, required, additionalProperties, items, bounds, lengths,
+// pattern and allOf/anyOf/oneOf/not), other keywords are ignored so a document is never wrongly rejected.
+// Subschemas using references are not followed and accept any value.
+func ValidateJSONSchema(schema interface{}, document interface{}) []error {
+	return validateJSONSchema(schema, document, "$")
+}
+
+func validateJSONSchema(schema interface{}, value interface{}, path string) []error {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		// boolean schemas: false rejects everything, true accepts everything
+		if b, isBool := schema.(bool); isBool && !b {
+			return []error{fmt.Errorf("%s: is not allowed", path)}
+		}
+		return nil
+	}
+	// references are not resolved, and keywords next to a reference may be ignored by the schema draft
+	for _, keyword := range []string{"$ref", "$dynamicRef", "$recursiveRef"} {
+		if _, ok := s[keyword]; ok {
+			return nil
+		}
+	}
+
+	if t, ok := s["type"]; ok {
+		if err := validateJSONSchemaType(t, value, path); err != nil {
+			// other keywords are meaningless on a value of wrong type
+			return []error{err}
+		}
+	}
+
+	errs := make([]error, 0)
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: must be one of %s", path, jsonString(enum)))
+		}
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, value) {
+		errs = append(errs, fmt.Errorf("%s: must be %s", path, jsonString(c)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, validateJSONSchemaObject(s, v, path)...)
+	case []interface{}:
+		errs = append(errs, validateJSONSchemaArray(s, v, path)...)
+	case string:
+		errs = append(errs, validateJSONSchemaString(s, v, path)...)
+	case float64:
+		errs = append(errs, validateJSONSchemaNumber(s, v, path)...)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, validateJSONSchema(sub, value, path)...)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok && countJSONSchemaMatches(anyOf, value, path) == 0 {
+		errs = append(errs, fmt.Errorf("%s: must match at least one schema of anyOf", path))
+	}
+	if oneOf, ok := s["oneOf"].([]interface{}); ok && countJSONSchemaMatches(oneOf, value, path) != 1 {
+		errs = append(errs, fmt.Errorf("%s: must match exactly one schema of oneOf", path))
+	}
+	if not, ok := s["not"]; ok && len(validateJSONSchema(not, value, path)) == 0 {
+		errs = append(errs, fmt.Errorf("%s: must not match schema of not", path))
+	}
+	return errs
+}
+
+func validateJSONSchemaType(t interface{}, value interface{}, path string) error {
+	types := make([]string, 0)
+	switch tv := t.(type) {
+	case string:
+		types = append(types, tv)
+	case []interface{}:
+		for _, e := range tv {
+			if s, ok := e.(string); ok {
+				types = append(types, s)
+			}
+		}
+	default:
+		return nil
+	}
+	for _, expected := range types {
+		if jsonTypeMatch(expected, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: must be of type %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+}
+
+func validateJSONSchemaObject(s map[string]interface{}, v map[string]interface{}, path string) []error {
+	errs := make([]error, 0)
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing required property '%s'", path, name))
+			}
+		}
+	}
+	properties, _ := s["properties"].(map[string]interface{})
+	patternProperties, validPatterns := jsonSchemaPatternProperties(s)
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		propPath := path + "." + k
+		matched := false
+		if propSchema, ok := properties[k]; ok {
+			errs = append(errs, validateJSONSchema(propSchema, v[k], propPath)...)
+			matched = true
+		}
+		for _, p := range patternProperties {
+			if p.re.MatchString(k) {
+				errs = append(errs, validateJSONSchema(p.schema, v[k], propPath)...)
+				matched = true
+			}
+		}
+		// a pattern not supported by go regexp may match the property
+		if matched || !validPatterns {
+			continue
+		}
+		switch additional := s["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, fmt.Errorf("%s: additional property '%s' is not allowed", path, k))
+			}
+		case map[string]interface{}:
+			errs = append(errs, validateJSONSchema(additional, v[k], propPath)...)
+		}
+	}
+	if min, ok := jsonNumber(s["minProperties"]); ok && float64(len(v)) < min {
+		errs = append(errs, fmt.Errorf("%s: must have at least %v properties", path, min))
+	}
+	if max, ok := jsonNumber(s["maxProperties"]); ok && float64(len(v)) > max {
+		errs = append(errs, fmt.Errorf("%s: must have at most %v properties", path, max))
+	}
+	return errs
+}
+
+type jsonSchemaPatternProperty struct {
+	re     *regexp.Regexp
+	schema interface{}
+}
+
+// jsonSchemaPatternProperties returns patternProperties of the schema sorted by pattern,
+// patterns not supported by go regexp are left out and reported by the returned boolean
+func jsonSchemaPatternProperties(s map[string]interface{}) ([]jsonSchemaPatternProperty, bool) {
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	keys := make([]string, 0, len(patterns))
+	for k := range patterns {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	valid := true
+	props := make([]jsonSchemaPatternProperty, 0, len(keys))
+	for _, k := range keys {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			valid = false
+			continue
+		}
+		props = append(props, jsonSchemaPatternProperty{re: re, schema: patterns[k]})
+	}
+	return props, valid
+}
+
+func validateJSONSchemaArray(s map[string]interface{}, v []interface{}, path string) []error {
+	errs := make([]error, 0)
+	switch items := s["items"].(type) {
+	case map[string]interface{}:
+		for i, e := range v {
+			errs = append(errs, validateJSONSchema(items, e, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case []interface{}:
+		for i, e := range v {
+			if i < len(items) {
+				errs = append(errs, validateJSONSchema(items[i], e, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	if min, ok := jsonNumber(s["minItems"]); ok && float64(len(v)) < min {
+		errs = append(errs, fmt.Errorf("%s: must have at least %v items", path, min))
+	}
+	if max, ok := jsonNumber(s["maxItems"]); ok && float64(len(v)) > max {
+		errs = append(errs, fmt.Errorf("%s: must have at most %v items", path, max))
+	}
+	if unique, ok := s["uniqueItems"].(bool); ok && unique {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if jsonEqual(v[i], v[j]) {
+					errs = append(errs, fmt.Errorf("%s: items %d and %d are not unique", path, i, j))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func validateJSONSchemaString(s map[string]interface{}, v string, path string) []error {
+	errs := make([]error, 0)
+	length := float64(len([]rune(v)))
+	if min, ok := jsonNumber(s["minLength"]); ok && length < min {
+		errs = append(errs, fmt.Errorf("%s: must be at least %v characters long", path, min))
+	}
+	if max, ok := jsonNumber(s["maxLength"]); ok && length > max {
+		errs = append(errs, fmt.Errorf("%s: must be at most %v characters long", path, max))
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		// patterns not supported by go regexp are ignored
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+			errs = append(errs, fmt.Errorf("%s: must match pattern '%s'", path, pattern))
+		}
+	}
+	return errs
+}
+
+func validateJSONSchemaNumber(s map[string]interface{}, v float64, path string) []error {
+	errs := make([]error, 0)
+	if min, ok := jsonNumber(s["minimum"]); ok {
+		// draft 4 declares exclusive bounds as boolean modifiers
+		if exclusive, _ := s["exclusiveMinimum"].(bool); exclusive && v <= min {
+			errs = append(errs, fmt.Errorf("%s: must be greater than %v", path, min))
+		} else if v < min {
+			errs = append(errs, fmt.Errorf("%s: must be greater than or equal to %v", path, min))
+		}
+	}
+	if max, ok := jsonNumber(s["maximum"]); ok {
+		if exclusive, _ := s["exclusiveMaximum"].(bool); exclusive && v >= max {
+			errs = append(errs, fmt.Errorf("%s: must be less than %v", path, max))
+		} else if v > max {
+			errs = append(errs, fmt.Errorf("%s: must be less than or equal to %v", path, max))
+		}
+	}
+	if min, ok := jsonNumber(s["exclusiveMinimum"]); ok && v <= min {
+		errs = append(errs, fmt.Errorf("%s: must be greater than %v", path, min))
+	}
+	if max, ok := jsonNumber(s["exclusiveMaximum"]); ok && v >= max {
+		errs = append(errs, fmt.Errorf("%s: must be less than %v", path, max))
+	}
+	if multiple, ok := jsonNumber(s["multipleOf"]); ok && multiple > 0 {
+		if q := v / multiple; math.Abs(q-math.Round(q)) > 1e-9 {
+			errs = append(errs, fmt.Errorf("%s: must be a multiple of %v", path, multiple))
+		}
+	}
+	return errs
+}
+
+func countJSONSchemaMatches(schemas []interface{}, value interface{}, path string) int {
+	matches := 0
+	for _, sub := range schemas {
+		if len(validateJSONSchema(sub, value, path)) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+func jsonTypeMatch(expected string, value interface{}) bool {
+	switch expected {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonTypeName(value) == expected
+	}
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonNumber(v interface{}) (float64, bool) {
+	n, ok := v.(float64)
+	return n, ok
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
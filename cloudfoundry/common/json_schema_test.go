@@ -0,0 +1,129 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testPlanSchema = `{
+	"$schema": "http://json-schema.org/draft-04/schema#",
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["size"],
+	"properties": {
+		"size": {"type": "string", "enum": ["small", "large"]},
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+		"name": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 8},
+		"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true}
+	}
+}`
+
+func decodeJSON(t *testing.T, s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := decodeJSON(t, testPlanSchema)
+
+	cases := []struct {
+		document string
+		errors   []string
+	}{
+		{`{"size": "small", "replicas": 3, "name": "db", "tags": ["a", "b"]}`, nil},
+		{`{}`, []string{"$: missing required property 'size'"}},
+		{`{"size": "medium"}`, []string{`$.size: must be one of ["small","large"]`}},
+		{`{"size": "small", "replicas": 2.5}`, []string{"$.replicas: must be of type integer, got number"}},
+		{`{"size": "small", "replicas": 6}`, []string{"$.replicas: must be less than or equal to 5"}},
+		{`{"size": "small", "name": "Db"}`, []string{"$.name: must match pattern '^[a-z]+$'"}},
+		{`{"size": "small", "tags": ["a", 1]}`, []string{"$.tags[1]: must be of type string, got number"}},
+		{`{"size": "small", "other": true}`, []string{"$: additional property 'other' is not allowed"}},
+		{`[]`, []string{"$: must be of type object, got array"}},
+	}
+
+	for _, c := range cases {
+		errs := ValidateJSONSchema(schema, decodeJSON(t, c.document))
+		if len(errs) != len(c.errors) {
+			t.Errorf("document %s: expected errors %v, got %v", c.document, c.errors, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != c.errors[i] {
+				t.Errorf("document %s: expected error '%s', got '%s'", c.document, c.errors[i], err.Error())
+			}
+		}
+	}
+}
+
+func TestValidateJSONSchema_combinators(t *testing.T) {
+	schema := decodeJSON(t, `{"oneOf": [{"type": "string"}, {"type": "integer", "exclusiveMinimum": 0}]}`)
+
+	if errs := ValidateJSONSchema(schema, decodeJSON(t, `"a"`)); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+	if errs := ValidateJSONSchema(schema, decodeJSON(t, `0`)); len(errs) != 1 {
+		t.Errorf("expected oneOf error, got %v", errs)
+	}
+}
+
+func TestValidateJSONSchema_patternProperties(t *testing.T) {
+	schema := decodeJSON(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {"size": {"type": "string"}},
+		"patternProperties": {"^x-": {"type": "integer"}, "^label_": {"type": "string"}}
+	}`)
+
+	cases := []struct {
+		document string
+		errors   []string
+	}{
+		{`{"size": "small", "x-replicas": 2, "label_env": "dev"}`, nil},
+		{`{"x-replicas": "two"}`, []string{"$.x-replicas: must be of type integer, got string"}},
+		{`{"other": true}`, []string{"$: additional property 'other' is not allowed"}},
+	}
+	for _, c := range cases {
+		errs := ValidateJSONSchema(schema, decodeJSON(t, c.document))
+		if len(errs) != len(c.errors) {
+			t.Errorf("document %s: expected errors %v, got %v", c.document, c.errors, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err.Error() != c.errors[i] {
+				t.Errorf("document %s: expected error '%s', got '%s'", c.document, c.errors[i], err.Error())
+			}
+		}
+	}
+
+	// a pattern go regexp can't compile may match any property
+	schema = decodeJSON(t, `{"additionalProperties": false, "patternProperties": {"^(?!internal)": {}}}`)
+	if errs := ValidateJSONSchema(schema, decodeJSON(t, `{"other": true}`)); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+}
+
+func TestValidateJSONSchema_references(t *testing.T) {
+	schema := decodeJSON(t, `{
+		"definitions": {"size": {"type": "string", "enum": ["small"]}},
+		"type": "object",
+		"properties": {
+			"size": {"$ref": "#/definitions/size"},
+			"disk": {"$ref": "#/definitions/disk", "type": "string"},
+			"nested": {"type": "object", "properties": {"count": {"$ref": "#/definitions/count"}}}
+		}
+	}`)
+
+	if errs := ValidateJSONSchema(schema, decodeJSON(t, `{"size": 3, "disk": 10, "nested": {"count": "many"}}`)); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+	// what is understood next to references is still validated
+	if errs := ValidateJSONSchema(schema, decodeJSON(t, `{"nested": []}`)); len(errs) != 1 {
+		t.Errorf("expected nested type error, got %v", errs)
+	}
+	if errs := ValidateJSONSchema(decodeJSON(t, `{"$ref": "#/definitions/root"}`), decodeJSON(t, `[]`)); len(errs) != 0 {
+		t.Errorf("expected no error, got %v", errs)
+	}
+}
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -42,3 +43,15 @@ func PollingWithTimeout(pollingFunc func() (bool, error), waitTime time.Duration
 	}
 	return nil
 }
+
+// PollingWithContext is PollingWithTimeout which also stops as soon as one of the given contexts is done
+func PollingWithContext(pollingFunc func() (bool, error), waitTime time.Duration, timeout time.Duration, ctxs ...context.Context) error {
+	return PollingWithTimeout(func() (bool, error) {
+		for _, ctx := range ctxs {
+			if err := ctx.Err(); err != nil {
+				return true, err
+			}
+		}
+		return pollingFunc()
+	}, waitTime, timeout)
+}
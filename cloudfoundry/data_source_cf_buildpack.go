@@ -0,0 +1,85 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceBuildpack() *schema.Resource {
+
+	return &schema.Resource{
+
+		ReadContext: dataSourceBuildpackRead,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"stack": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"lifecycle": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{buildpackLifecycleBuildpack, buildpackLifecycleCNB}, false),
+			},
+			"position": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"locked": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"filename": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			labelsKey:      labelsSchema(),
+			annotationsKey: annotationsSchema(),
+		},
+	}
+}
+
+func dataSourceBuildpackRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+
+	session := meta.(*managers.Session)
+	if session == nil {
+		return diag.Errorf("client is nil")
+	}
+
+	name := d.Get("name").(string)
+	bps, err := listBuildpacksV3(session, name, d.Get("stack").(string), d.Get("lifecycle").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(bps) == 0 {
+		return diag.FromErr(NotFound)
+	}
+	if len(bps) > 1 {
+		return diag.FromErr(fmt.Errorf("Found %d buildpacks named '%s', set stack or lifecycle to select one", len(bps), name))
+	}
+	d.SetId(bps[0].GUID)
+	setBuildpackV3State(d, bps[0])
+
+	err = metadataRead(buildpackMetadata, d, meta, true)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
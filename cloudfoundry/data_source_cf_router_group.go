@@ -25,6 +25,15 @@ func dataSourceRouterGroup() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"reservable_ports": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"free_ports": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+			},
 		},
 	}
 }
@@ -47,5 +56,15 @@ func dataSourceRouterGroupRead(ctx context.Context, d *schema.ResourceData, meta
 	}
 	d.SetId(routerGroup.GUID)
 	d.Set("type", routerGroup.Type)
+	d.Set("reservable_ports", routerGroup.ReservablePorts)
+
+	freePorts := make([]int, 0)
+	if routerGroup.ReservablePorts != "" {
+		freePorts, err = routerGroupFreePorts(session, routerGroup)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	d.Set("free_ports", freePorts)
 	return nil
 }
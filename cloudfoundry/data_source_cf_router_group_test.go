@@ -32,6 +32,10 @@ func TestAccDataSourceRouterGroup_normal(t *testing.T) {
 						checkDataSourceRouterGroupExists(ref),
 						resource.TestCheckResourceAttr(
 							ref, "name", "default-tcp"),
+						resource.TestCheckResourceAttrSet(
+							ref, "reservable_ports"),
+						resource.TestCheckResourceAttrSet(
+							ref, "free_ports.#"),
 					),
 				},
 			},
@@ -63,6 +67,9 @@ func checkDataSourceRouterGroupExists(resource string) resource.TestCheckFunc {
 		if err := assertSame(rgType, routerGroup.Type); err != nil {
 			return err
 		}
+		if err := assertSame(rs.Primary.Attributes["reservable_ports"], routerGroup.ReservablePorts); err != nil {
+			return err
+		}
 
 		return nil
 	}
@@ -0,0 +1,184 @@
+package cloudfoundry
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceServiceOfferings() *schema.Resource {
+
+	return &schema.Resource{
+
+		ReadContext: dataSourceServiceOfferingsRead,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service offerings with this name",
+			},
+			"service_broker": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service offerings of the service broker with this guid",
+			},
+			"space": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service offerings available in the space with this guid",
+			},
+			"label_selector": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service offerings matching this label selector (e.g. env=prod,tier in (a,b))",
+			},
+			"service_offerings": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"available": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"tags": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"shareable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"documentation_url": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"broker_catalog_id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"bindable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"plan_updateable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"instances_retrievable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"bindings_retrievable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"service_broker": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"labels": &schema.Schema{
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceServiceOfferingsRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	query := serviceListQuery(d, map[string]string{
+		"name":           "names",
+		"service_broker": "service_broker_guids",
+		"space":          "space_guids",
+		"label_selector": "label_selector",
+	})
+
+	offerings := make([]*resource.ServiceOffering, 0)
+	err := rawJSONListAll(session, "/v3/service_offerings", query, func(resources json.RawMessage) error {
+		var page []*resource.ServiceOffering
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		offerings = append(offerings, page...)
+		return nil
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	offeringsTf := make([]interface{}, 0, len(offerings))
+	for _, o := range offerings {
+		broker := ""
+		if o.Relationships.ServiceBroker.Data != nil {
+			broker = o.Relationships.ServiceBroker.Data.GUID
+		}
+		labels := make(map[string]interface{})
+		if o.Metadata != nil {
+			for k, v := range o.Metadata.Labels {
+				if v != nil {
+					labels[k] = *v
+				}
+			}
+		}
+		offeringsTf = append(offeringsTf, map[string]interface{}{
+			"id":                    o.GUID,
+			"name":                  o.Name,
+			"description":           o.Description,
+			"available":             o.Available,
+			"tags":                  o.Tags,
+			"shareable":             o.Shareable,
+			"documentation_url":     o.DocumentationURL,
+			"broker_catalog_id":     o.BrokerCatalog.ID,
+			"bindable":              o.BrokerCatalog.Features.Bindable,
+			"plan_updateable":       o.BrokerCatalog.Features.PlanUpdateable,
+			"instances_retrievable": o.BrokerCatalog.Features.InstancesRetrievable,
+			"bindings_retrievable":  o.BrokerCatalog.Features.BindingsRetrievable,
+			"service_broker":        broker,
+			"labels":                labels,
+		})
+	}
+
+	d.SetId(fmt.Sprintf("service_offerings-%x", sha1.Sum([]byte(query.Encode()))))
+	_ = d.Set("service_offerings", offeringsTf)
+	return nil
+}
+
+// serviceListQuery builds a v3 list query from data source attributes, filters maps attribute names to query keys
+func serviceListQuery(d *schema.ResourceData, filters map[string]string) url.Values {
+	query := url.Values{}
+	for attr, key := range filters {
+		if v, ok := d.GetOk(attr); ok {
+			query.Set(key, v.(string))
+		}
+	}
+	return query
+}
@@ -0,0 +1,47 @@
+package cloudfoundry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+const serviceOfferingsDataResource = `
+
+data "cloudfoundry_service_offerings" "test" {
+    name = "%s"
+}
+`
+
+func TestAccDataSourceServiceOfferings_normal(t *testing.T) {
+
+	serviceName1, _, _ := getTestServiceBrokers(t)
+
+	ref := "data.cloudfoundry_service_offerings.test"
+
+	resource.ParallelTest(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceOfferingsDataResource,
+						serviceName1),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr(
+							ref, "service_offerings.#", "1"),
+						resource.TestCheckResourceAttr(
+							ref, "service_offerings.0.name", serviceName1),
+						resource.TestCheckResourceAttrSet(
+							ref, "service_offerings.0.id"),
+						resource.TestCheckResourceAttrSet(
+							ref, "service_offerings.0.service_broker"),
+						resource.TestCheckResourceAttrSet(
+							ref, "service_offerings.0.broker_catalog_id"),
+					),
+				},
+			},
+		})
+}
@@ -0,0 +1,238 @@
+package cloudfoundry
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func dataSourceServicePlans() *schema.Resource {
+
+	return &schema.Resource{
+
+		ReadContext: dataSourceServicePlansRead,
+
+		Schema: map[string]*schema.Schema{
+
+			"name": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service plans with this name",
+			},
+			"service_offering": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service plans of the service offering with this guid",
+			},
+			"service_broker": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service plans of the service broker with this guid",
+			},
+			"space": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service plans available in the space with this guid",
+			},
+			"label_selector": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Only return service plans matching this label selector (e.g. env=prod,tier in (a,b))",
+			},
+			"service_plans": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"available": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"free": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"costs": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"amount": &schema.Schema{
+										Type:     schema.TypeFloat,
+										Computed: true,
+									},
+									"currency": &schema.Schema{
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"unit": &schema.Schema{
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
+							},
+						},
+						"visibility_type": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"maintenance_info_version": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"maintenance_info_description": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"broker_catalog_id": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"bindable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"plan_updateable": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"service_offering": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"service_instance_create_schema": &schema.Schema{
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "JSON schema of parameters accepted when creating a service instance",
+						},
+						"service_instance_update_schema": &schema.Schema{
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "JSON schema of parameters accepted when updating a service instance",
+						},
+						"service_binding_create_schema": &schema.Schema{
+							Type:        schema.TypeString,
+							Computed:    true,
+							Description: "JSON schema of parameters accepted when creating a service binding",
+						},
+						"labels": &schema.Schema{
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceServicePlansRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	query := serviceListQuery(d, map[string]string{
+		"name":             "names",
+		"service_offering": "service_offering_guids",
+		"service_broker":   "service_broker_guids",
+		"space":            "space_guids",
+		"label_selector":   "label_selector",
+	})
+
+	plans, err := listServicePlansV3(session, query)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	plansTf := make([]interface{}, 0, len(plans))
+	for _, p := range plans {
+		costs := make([]interface{}, 0, len(p.Costs))
+		for _, c := range p.Costs {
+			costs = append(costs, map[string]interface{}{
+				"amount":   c.Amount,
+				"currency": c.Currency,
+				"unit":     c.Unit,
+			})
+		}
+		offering := ""
+		if p.Relationships.ServiceOffering.Data != nil {
+			offering = p.Relationships.ServiceOffering.Data.GUID
+		}
+		labels := make(map[string]interface{})
+		if p.Metadata != nil {
+			for k, v := range p.Metadata.Labels {
+				if v != nil {
+					labels[k] = *v
+				}
+			}
+		}
+		plansTf = append(plansTf, map[string]interface{}{
+			"id":                             p.GUID,
+			"name":                           p.Name,
+			"description":                    p.Description,
+			"available":                      p.Available,
+			"free":                           p.Free,
+			"costs":                          costs,
+			"visibility_type":                p.VisibilityType,
+			"maintenance_info_version":       p.MaintenanceInfo.Version,
+			"maintenance_info_description":   p.MaintenanceInfo.Description,
+			"broker_catalog_id":              p.BrokerCatalog.ID,
+			"bindable":                       p.BrokerCatalog.Features.Bindable,
+			"plan_updateable":                p.BrokerCatalog.Features.PlanUpdateable,
+			"service_offering":               offering,
+			"service_instance_create_schema": servicePlanSchemaToString(p.Schemas.ServiceInstance.Create),
+			"service_instance_update_schema": servicePlanSchemaToString(p.Schemas.ServiceInstance.Update),
+			"service_binding_create_schema":  servicePlanSchemaToString(p.Schemas.ServiceBinding.Create),
+			"labels":                         labels,
+		})
+	}
+
+	d.SetId(fmt.Sprintf("service_plans-%x", sha1.Sum([]byte(query.Encode()))))
+	_ = d.Set("service_plans", plansTf)
+	return nil
+}
+
+// listServicePlansV3 lists service plans through raw client to let label selector be given as is
+func listServicePlansV3(session *managers.Session, query url.Values) ([]*resource.ServicePlan, error) {
+	plans := make([]*resource.ServicePlan, 0)
+	err := rawJSONListAll(session, "/v3/service_plans", query, func(resources json.RawMessage) error {
+		var page []*resource.ServicePlan
+		if err := json.Unmarshal(resources, &page); err != nil {
+			return err
+		}
+		plans = append(plans, page...)
+		return nil
+	})
+	return plans, err
+}
+
+// servicePlanSchemaToString returns parameters json schema of a plan, empty when the broker does not define one
+func servicePlanSchemaToString(s resource.ServicePlanSchemaCreateOrUpdate) string {
+	if s.Parameters == nil {
+		return ""
+	}
+	schemaJSON, err := normalizeJSONParams(string(*s.Parameters))
+	if err != nil {
+		return string(*s.Parameters)
+	}
+	return schemaJSON
+}
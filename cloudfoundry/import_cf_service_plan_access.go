@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
@@ -14,6 +15,19 @@ func resourceServicePlanAccessImport(ctx context.Context, d *schema.ResourceData
 		return []*schema.ResourceData{}, fmt.Errorf("client is nil")
 	}
 
+	// visibility managed through v3 api is imported from plan id prefixed by visibility/
+	if strings.HasPrefix(d.Id(), servicePlanVisibilityImportPrefix) {
+		plan := strings.TrimPrefix(d.Id(), servicePlanVisibilityImportPrefix)
+		spV, err := session.ClientGo.ServicePlansVisibility.Get(ctx, plan)
+		if err != nil {
+			return []*schema.ResourceData{}, err
+		}
+		d.SetId(plan)
+		d.Set("plan", plan)
+		setServicePlanVisibility(d, spV)
+		return ImportReadContext(resourceServicePlanAccessRead)(ctx, d, meta)
+	}
+
 	spV, _, err := session.ClientV2.GetServicePlanVisibility(d.Id())
 	if err == nil {
 		d.Set("plan", spV.ServicePlanGUID)
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
 )
 
 type AppDeploy struct {
@@ -11,6 +12,7 @@ type AppDeploy struct {
 	Mappings        []ccv2.RouteMapping
 	ServiceBindings []ccv2.ServiceBinding
 	Path            string
+	PathDownload    bits.DownloadOptions
 	BindTimeout     time.Duration
 	StageTimeout    time.Duration
 	StartTimeout    time.Duration
@@ -78,6 +78,7 @@ func (s BlueGreenV2) Deploy(appDeploy AppDeploy) (AppDeployResponse, error) {
 					ServiceBindings: appDeploy.ServiceBindings,
 					Mappings:        appDeploy.Mappings,
 					Path:            appDeploy.Path,
+					PathDownload:    appDeploy.PathDownload,
 					StageTimeout:    appDeploy.StageTimeout,
 					BindTimeout:     appDeploy.BindTimeout,
 					StartTimeout:    appDeploy.StartTimeout,
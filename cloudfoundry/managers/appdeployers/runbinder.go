@@ -1,24 +1,28 @@
 package appdeployers
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2/constant"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
-	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/noaa"
 )
 
 type RunBinder struct {
 	client     *ccv2.Client
-	noaaClient *noaa.NOAAClient
+	logsClient common.AppLogsClient
+	logsDir    string
 }
 
-func NewRunBinder(client *ccv2.Client, noaaClient *noaa.NOAAClient) *RunBinder {
+func NewRunBinder(client *ccv2.Client, logsClient common.AppLogsClient, logsDir string) *RunBinder {
 	return &RunBinder{
 		client:     client,
-		noaaClient: noaaClient,
+		logsClient: logsClient,
+		logsDir:    logsDir,
 	}
 }
 
@@ -127,6 +131,7 @@ func (r RunBinder) BindServiceInstances(appDeploy AppDeploy) ([]ccv2.ServiceBind
 }
 
 func (r RunBinder) WaitStart(appDeploy AppDeploy) error {
+	defer r.streamLogs(appDeploy, "startup")()
 	return common.PollingWithTimeout(func() (bool, error) {
 		appInstances, _, err := r.client.GetApplicationApplicationInstances(appDeploy.App.GUID)
 		if err != nil {
@@ -153,6 +158,7 @@ func (r RunBinder) WaitStart(appDeploy AppDeploy) error {
 }
 
 func (r RunBinder) WaitStaging(appDeploy AppDeploy) error {
+	defer r.streamLogs(appDeploy, "staging")()
 	err := common.PollingWithTimeout(func() (bool, error) {
 		app, _, err := r.client.GetApplication(appDeploy.App.GUID)
 		if err != nil {
@@ -223,12 +229,63 @@ func (r RunBinder) Restart(appDeploy AppDeploy, stageTimeout time.Duration) erro
 	return nil
 }
 
+// streamLogs follows app logs during a deployment step, returned func stops following
+func (r RunBinder) streamLogs(appDeploy AppDeploy, step string) func() {
+	return common.StreamAppLogs(r.logsClient, r.logsDir, appDeploy.App.GUID, appDeploy.App.Name, step)
+}
+
+// processDeployErr adds to a deployment failure recent logs and state of app instances,
+// each one as a detail of a common.DeployError
 func (r RunBinder) processDeployErr(origErr error, appDeploy AppDeploy) error {
-	var err error
-	var logs string
-	logs, err = r.noaaClient.RecentLogs(appDeploy.App.GUID)
+	var deployErr *common.DeployError
+	if errors.As(origErr, &deployErr) {
+		return origErr
+	}
+	deployErr = &common.DeployError{
+		Err: origErr,
+	}
+
+	logs, err := r.logsClient.RecentLogs(appDeploy.App.GUID)
 	if err != nil {
 		logs = fmt.Sprintf("Error occurred when recolting app %s logs: %s", appDeploy.App.Name, err.Error())
 	}
-	return fmt.Errorf("%s\n\nApp '%s' logs: \n%s", origErr.Error(), appDeploy.App.Name, logs)
+	deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+		Summary: fmt.Sprintf("App '%s' logs", appDeploy.App.Name),
+		Detail:  logs,
+	})
+
+	instances, err := r.instancesDiagnostic(appDeploy.App.GUID)
+	if err != nil {
+		instances = fmt.Sprintf("Error occurred when retrieving app instances: %s", err.Error())
+	}
+	if instances != "" {
+		deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+			Summary: fmt.Sprintf("App '%s' instances", appDeploy.App.Name),
+			Detail:  instances,
+		})
+	}
+	return deployErr
+}
+
+func (r RunBinder) instancesDiagnostic(appGUID string) (string, error) {
+	appInstances, _, err := r.client.GetApplicationApplicationInstances(appGUID)
+	if err != nil {
+		return "", err
+	}
+	indexes := make([]int, 0, len(appInstances))
+	for i := range appInstances {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	lines := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		instance := appInstances[i]
+		line := fmt.Sprintf("instance %d: state %s, since %s", i, instance.State,
+			time.Unix(int64(instance.Since), 0).In(time.Local).Format(common.LogTimestampFormat))
+		if instance.Details != "" {
+			line += fmt.Sprintf(", details: %s", instance.Details)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
 }
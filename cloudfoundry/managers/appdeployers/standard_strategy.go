@@ -100,7 +100,7 @@ func (s Standard) Deploy(appDeploy AppDeploy) (AppDeployResponse, error) {
 					return ctx, nil
 				}
 				appResp := ctx["app_response"].(AppDeployResponse)
-				err := s.bitsManager.UploadApp(appResp.App.GUID, appDeploy.Path)
+				err := s.bitsManager.UploadApp(appResp.App.GUID, appDeploy.Path, appDeploy.PathDownload)
 				if err != nil {
 					return ctx, err
 				}
@@ -6,11 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
-	"mime"
-	"mime/multipart"
 	"net/http"
-	"net/textproto"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,6 +40,17 @@ type ZipFile struct {
 	filesize int64
 }
 
+// tempZipFile is a zip built for the upload, it is removed when closed
+type tempZipFile struct {
+	*os.File
+}
+
+func (f tempZipFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
 // NewBitsManager -
 func NewBitsManager(clientV2 *ccv2.Client, clientV3 *ccv3.Client, rawClient *raw.RawClient, httpClient *http.Client) *BitsManager {
 	return &BitsManager{
@@ -86,58 +93,50 @@ func (m BitsManager) CopyApp(origAppGuid string, newAppGuid string) error {
 // uri path can be:
 // - file:///path/to/my/buildpack.zip
 // - http(s)://awesome.buildpack.com/my-buildpack.zip
-func (m BitsManager) UploadBuildpack(buildpackGUID string, bpPath string) error {
-	zipFile, err := m.RetrieveZip(bpPath)
+// remote buildpack is downloaded with given options (authentication, expected checksum)
+// Upload is made on v3 api which accepts both buildpack (zip) and cnb (cnb/tgz) lifecycles
+func (m BitsManager) UploadBuildpack(buildpackGUID string, bpPath string, lifecycle string, opts DownloadOptions) error {
+	zipFile, err := m.RetrieveZip(bpPath, opts)
 	if err != nil {
 		return err
 	}
-	zipFileReader := zipFile.r
-	baseName := zipFile.baseName
-	fileSize := zipFile.filesize
-	defer zipFileReader.Close()
-	apiURL := fmt.Sprintf("/v2/buildpacks/%s/bits", buildpackGUID)
-
-	r, w := io.Pipe()
-	mpw := multipart.NewWriter(w)
-	go func() {
-		var err error
-		defer w.Close()
-
-		h := make(textproto.MIMEHeader)
-		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="buildpack"; filename="%s"`, baseName))
-		h.Set("Content-Type", "application/zip")
-		h.Set("Content-Length", fmt.Sprintf("%d", fileSize))
-		h.Set("Content-Transfer-Encoding", "binary")
-
-		part, err := mpw.CreatePart(h)
-		if err != nil {
-			mpw.Close()
-			panic(err)
-		}
-		if _, err = io.Copy(part, zipFileReader); err != nil {
-			mpw.Close()
-			panic(err)
-		}
-		mpw.Close()
-	}()
+	defer zipFile.r.Close()
 
-	req, err := m.rawClient.NewRequest("PUT", apiURL, nil)
+	upload, err := newMultipartUpload(nil, uploadFile{
+		fieldName:   "bits",
+		fileName:    zipFile.baseName,
+		contentType: buildpackContentType(lifecycle, zipFile.baseName),
+		r:           zipFile.r,
+		size:        zipFile.filesize,
+	})
 	if err != nil {
 		return err
 	}
-	contentType := fmt.Sprintf("multipart/form-data; boundary=%s", mpw.Boundary())
-	req.Header.Set("Content-Type", contentType)
-	req.ContentLength = m.predictPartBuildpack(baseName, fileSize, mpw.Boundary())
-	req.Body = r
-
-	resp, err := m.rawClient.Do(req)
+	header, err := m.upload(http.MethodPost, fmt.Sprintf("/v3/buildpacks/%s/upload", buildpackGUID), upload)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// buildpack is processed asynchronously by the job given in location
+	if location := header.Get("Location"); location != "" {
+		_, err = m.clientV3.PollJob(ccv3.JobURL(location))
+		return err
 	}
-	defer resp.Body.Close()
 	return nil
 }
 
+// buildpackContentType gives the content type of a buildpack file for its lifecycle,
+// a cnb is either a tar archive (.cnb) or a gzipped tar archive (.tgz)
+func buildpackContentType(lifecycle string, fileName string) string {
+	if lifecycle != "cnb" {
+		return "application/zip"
+	}
+	if strings.HasSuffix(fileName, ".tgz") || strings.HasSuffix(fileName, ".gz") {
+		return "application/gzip"
+	}
+	return "application/x-tar"
+}
+
 // GetAppEnvironmentVariables - Get app environment variables
 func (m BitsManager) GetAppEnvironmentVariables(appGUID string) (map[string]interface{}, error) {
 	apiURL := fmt.Sprintf("/v3/apps/%s/environment_variables", appGUID)
@@ -214,179 +213,98 @@ func (m BitsManager) UpdateAppEnvironment(appGUID string, env map[string]interfa
 }
 
 // UploadApp - Upload a zip file containing app code to cloud foundry in full stream
-func (m BitsManager) UploadApp(appGUID string, path string) error {
-	zipFile, err := m.RetrieveZip(path)
+// only files unknown by cloud controller are uploaded, remote app is downloaded with given options first
+func (m BitsManager) UploadApp(appGUID string, path string, opts DownloadOptions) error {
+	path, _, err := m.localZip(path, opts)
 	if err != nil {
 		return err
 	}
-	zipFileReader := zipFile.r
-	fileSize := zipFile.filesize
-	defer zipFileReader.Close()
-	apiURL := fmt.Sprintf("/v2/apps/%s/bits", appGUID)
-	r, w := io.Pipe()
-	mpw := multipart.NewWriter(w)
-	go func() {
-		var err error
-		defer w.Close()
-		part, err := mpw.CreateFormField("resources")
-		if err != nil {
-			mpw.Close()
-			panic(err)
-		}
-		_, err = io.Copy(part, bytes.NewBuffer([]byte("[]")))
-		if err != nil {
-			mpw.Close()
-			panic(err)
-		}
-		h := make(textproto.MIMEHeader)
-		h.Set("Content-Disposition", `form-data; name="application"; filename="application.zip"`)
-		h.Set("Content-Type", "application/zip")
-		h.Set("Content-Length", fmt.Sprintf("%d", fileSize))
-		h.Set("Content-Transfer-Encoding", "binary")
-
-		part, err = mpw.CreatePart(h)
+	appBits, err := m.prepareAppBits(path)
+	if err != nil {
+		return err
+	}
+	defer appBits.cleanup()
+	matched := make([]ccv3.V2FormattedResource, 0, len(appBits.matched))
+	for _, r := range appBits.matched {
+		matched = append(matched, r.ToV2FormattedResource())
+	}
+	matchedJSON, err := json.Marshal(matched)
+	if err != nil {
+		return err
+	}
+	// application part is left out when cloud controller already has every file
+	var application uploadFile
+	if appBits.uploadZip {
+		zipFile, err := m.RetrieveZip(appBits.zipPath, DownloadOptions{})
 		if err != nil {
-			mpw.Close()
-			panic(err)
+			return err
 		}
-		if _, err = io.Copy(part, zipFileReader); err != nil {
-			mpw.Close()
-			panic(err)
+		defer zipFile.r.Close()
+		application = uploadFile{
+			fieldName: "application",
+			fileName:  "application.zip",
+			r:         zipFile.r,
+			size:      zipFile.filesize,
 		}
-		mpw.Close()
-	}()
-	req, err := m.rawClient.NewRequest("PUT", apiURL, nil)
-	if err != nil {
-		return err
 	}
-	contentType := fmt.Sprintf("multipart/form-data; boundary=%s", mpw.Boundary())
-	req.Header.Set("Content-Type", contentType)
-	req.ContentLength = m.predictPartApp(fileSize, mpw.Boundary())
-	req.Body = r
 
-	resp, err := m.rawClient.Do(req)
+	upload, err := newMultipartUpload([]formField{{name: "resources", value: matchedJSON}}, application)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer resp.Body.Close()
-	return nil
+	_, err = m.upload(http.MethodPut, fmt.Sprintf("/v2/apps/%s/bits", appGUID), upload)
+	return err
 }
 
-func (m BitsManager) predictPartApp(filesize int64, boundary string) int64 {
-	buf := new(bytes.Buffer)
-	mpw := multipart.NewWriter(buf)
-
-	err := mpw.SetBoundary(boundary)
-	if err != nil {
-		panic(err)
+// RetrieveZip opens a local zip, a remote zip downloaded with given options or an app directory zipped on the fly
+func (m BitsManager) RetrieveZip(path string, opts DownloadOptions) (ZipFile, error) {
+	if IsDirectory(path) && opts.SHA256 == "" {
+		return m.zipDirectory(strings.TrimPrefix(path, "file://"))
 	}
-	part, err := mpw.CreateFormField("resources")
+	path, baseName, err := m.localZip(path, opts)
 	if err != nil {
-		mpw.Close()
-		panic(err)
+		return ZipFile{}, err
 	}
-	_, err = io.Copy(part, bytes.NewBuffer([]byte("[]")))
+	f, err := os.Open(path)
 	if err != nil {
-		mpw.Close()
-		panic(err)
+		return ZipFile{}, err
 	}
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", `form-data; name="application"; filename="application.zip"`)
-	h.Set("Content-Type", "application/zip")
-	h.Set("Content-Length", fmt.Sprintf("%d", filesize))
-	h.Set("Content-Transfer-Encoding", "binary")
-
-	_, err = mpw.CreatePart(h)
+	stat, err := f.Stat()
 	if err != nil {
-		mpw.Close()
-		panic(err)
+		f.Close()
+		return ZipFile{}, err
 	}
-	mpw.Close()
-	b, _ := ioutil.ReadAll(buf)
-	return int64(len(b)) + filesize
+	return ZipFile{
+		r:        f,
+		baseName: baseName,
+		filesize: stat.Size(),
+	}, nil
 }
 
-func (m BitsManager) predictPartBuildpack(filename string, filesize int64, boundary string) int64 {
-	buf := new(bytes.Buffer)
-	mpw := multipart.NewWriter(buf)
-
-	err := mpw.SetBoundary(boundary)
+// zipDirectory zips an app directory in a temporary file removed once the zip is closed
+func (m BitsManager) zipDirectory(dir string) (ZipFile, error) {
+	files, err := GatherDirectory(dir)
 	if err != nil {
-		panic(err)
+		return ZipFile{}, err
 	}
-	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="buildpack"; filename="%s"`, filename))
-	h.Set("Content-Type", "application/zip")
-	h.Set("Content-Length", fmt.Sprintf("%d", filesize))
-	h.Set("Content-Transfer-Encoding", "binary")
-
-	_, err = mpw.CreatePart(h)
+	zipPath, err := ZipDirectoryToTemp(dir, files)
 	if err != nil {
-		mpw.Close()
-		panic(err)
-	}
-	mpw.Close()
-	b, _ := ioutil.ReadAll(buf)
-	return int64(len(b)) + filesize
-}
-
-func (m BitsManager) RetrieveZip(path string) (ZipFile, error) {
-	path = strings.TrimPrefix(path, "file://")
-	baseName := filepath.Base(path)
-	if strings.HasPrefix(path, "http") {
-		resp, err := m.httpClient.Get(path)
-		if err != nil {
-			return ZipFile{}, err
-		}
-		fileSize := resp.ContentLength
-		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-			return ZipFile{}, fmt.Errorf("Failed to download file %s (%s)", path, strings.Trim(resp.Status, " "))
-		}
-		_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
-		if err == nil {
-			var ok bool
-			if _, ok = params["filename"]; ok {
-				baseName = params["filename"]
-			}
-		}
-		if fileSize > 0 {
-			return ZipFile{
-				r:        resp.Body,
-				baseName: baseName,
-				filesize: fileSize,
-			}, nil
-		} else {
-			tempFile, err := ioutil.TempFile("", "")
-			if err != nil {
-				return ZipFile{}, err
-			}
-			defer os.Remove(tempFile.Name())
-			fileSize, err := io.Copy(tempFile, resp.Body)
-			if err != nil {
-				return ZipFile{}, err
-			}
-			_, err = tempFile.Seek(0, 0)
-			if err != nil {
-				return ZipFile{}, err
-			}
-			return ZipFile{
-				r:        tempFile,
-				baseName: baseName,
-				filesize: fileSize,
-			}, nil
-		}
+		return ZipFile{}, err
 	}
-	f, err := os.Open(path)
+	f, err := os.Open(zipPath)
 	if err != nil {
+		os.Remove(zipPath)
 		return ZipFile{}, err
 	}
+	zipFile := tempZipFile{f}
 	stat, err := f.Stat()
 	if err != nil {
+		zipFile.Close()
 		return ZipFile{}, err
 	}
 	return ZipFile{
-		r:        f,
-		baseName: baseName,
+		r:        zipFile,
+		baseName: filepath.Base(dir) + ".zip",
 		filesize: stat.Size(),
 	}, nil
 }
@@ -413,53 +331,41 @@ func (m BitsManager) CreateDockerPackage(appGUID string, dockerImage string, doc
 }
 
 // CreateAndUploadBitsPackage creates a new package and upload bits to the application
-func (m BitsManager) CreateAndUploadBitsPackage(appGUID string, path string, stageTimeout time.Duration) (resources.Package, ccv3.Warnings, error) {
+func (m BitsManager) CreateAndUploadBitsPackage(appGUID string, path string, opts DownloadOptions, stageTimeout time.Duration) (resources.Package, ccv3.Warnings, error) {
 	pkg, warnings, err := m.CreateBitsPackageByApplication(appGUID)
 
 	if err != nil {
 		return resources.Package{}, warnings, err
 	}
 
-	// get zip from remote artifactory
-	if strings.HasPrefix(path, "http") {
-		zipFile, err := m.RetrieveZip(path)
-		if err != nil {
-			return resources.Package{}, warnings, err
-		}
-
-		tempDir, err := ioutil.TempDir("", "temp-")
-		if err != nil {
-			return resources.Package{}, warnings, err
-		}
-
-		defer func() {
-			if err := os.RemoveAll(tempDir); err != nil {
-				log.Printf("Error removing temp dir %s: %v", tempDir, err)
-			}
-		}()
+	// remote zip is downloaded first
+	path, _, err = m.localZip(path, opts)
+	if err != nil {
+		return resources.Package{}, warnings, err
+	}
 
-		outputFile, err := os.Create(filepath.Join(tempDir, zipFile.baseName))
+	// only files unknown by cloud controller are uploaded
+	appBits, err := m.prepareAppBits(path)
+	if err != nil {
+		return resources.Package{}, warnings, err
+	}
+	defer appBits.cleanup()
+	var newResources io.Reader
+	var newResourcesLength int64
+	if appBits.uploadZip {
+		zipFile, err := os.Open(appBits.zipPath)
 		if err != nil {
 			return resources.Package{}, warnings, err
 		}
-
-		_, err = io.Copy(outputFile, zipFile.r)
+		defer zipFile.Close()
+		stat, err := zipFile.Stat()
 		if err != nil {
 			return resources.Package{}, warnings, err
 		}
-
-		if err := outputFile.Sync(); err != nil {
-			return resources.Package{}, warnings, err
-		}
-
-		if err := outputFile.Close(); err != nil {
-			return resources.Package{}, warnings, err
-		}
-
-		path = outputFile.Name()
+		newResources = zipFile
+		newResourcesLength = stat.Size()
 	}
-
-	_, warnings, err = m.clientV3.UploadPackage(pkg, path)
+	_, warnings, err = m.clientV3.UploadBitsPackage(pkg, appBits.matched, newResources, newResourcesLength)
 	if err != nil {
 		return resources.Package{}, warnings, err
 	}
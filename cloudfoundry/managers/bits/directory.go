@@ -0,0 +1,243 @@
+package bits
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ignore "github.com/sabhiram/go-gitignore"
+)
+
+// DefaultIgnoreLines are files never pushed from a directory, as done by cf cli, only the manifest at root is left out
+var DefaultIgnoreLines = []string{
+	".cfignore",
+	".DS_Store",
+	".git",
+	".gitignore",
+	".hg",
+	".svn",
+	"_darcs",
+	"/manifest.yml",
+}
+
+// zipModTime is the modification time set on every zip entry so that zipping the same files always gives the same zip
+var zipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// AppFile is a file or a directory to push from an app directory
+type AppFile struct {
+	// Path is the slash separated path of the file relative to the app directory
+	Path string
+	Mode os.FileMode
+	Size int64
+	// SHA1 is the hex encoded sha1 of the file content, or of the link target for symlinks, empty for directories
+	SHA1 string
+}
+
+func (f AppFile) IsDir() bool {
+	return f.Mode.IsDir()
+}
+
+func (f AppFile) IsSymlink() bool {
+	return f.Mode&os.ModeSymlink != 0
+}
+
+// IsDirectory returns true when path (optionally prefixed by file://) is a local directory
+func IsDirectory(path string) bool {
+	stat, err := os.Stat(strings.TrimPrefix(path, "file://"))
+	return err == nil && stat.IsDir()
+}
+
+// GatherDirectory lists files of an app directory sorted by path, files matching .cfignore
+// of the directory or DefaultIgnoreLines are left out
+func GatherDirectory(dir string) ([]AppFile, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	ignoreLines := DefaultIgnoreLines
+	cfIgnore, err := os.ReadFile(filepath.Join(dir, ".cfignore"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		ignoreLines = append(append([]string{}, DefaultIgnoreLines...), strings.Split(string(cfIgnore), "\n")...)
+	}
+	matcher := ignore.CompileIgnoreLines(ignoreLines...)
+
+	files := make([]AppFile, 0)
+	err = filepath.Walk(dir, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, fullPath)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		// patterns ending with a slash only match directories given with a trailing slash
+		if info.IsDir() && (matcher.MatchesPath(relPath) || matcher.MatchesPath(relPath+"/")) {
+			// nothing in an ignored directory is pushed
+			return filepath.SkipDir
+		}
+		if matcher.MatchesPath(relPath) {
+			return nil
+		}
+
+		file := AppFile{
+			Path: relPath,
+			Mode: info.Mode(),
+		}
+		switch {
+		case info.IsDir():
+			file.Mode = os.ModeDir | 0755
+		case info.Mode()&os.ModeSymlink != 0:
+			file.Mode = os.ModeSymlink | 0777
+			target, err := os.Readlink(fullPath)
+			if err != nil {
+				return err
+			}
+			file.SHA1 = fmt.Sprintf("%x", sha1.Sum([]byte(filepath.ToSlash(target))))
+		default:
+			file.Mode = info.Mode().Perm()
+			file.Size = info.Size()
+			file.SHA1, err = fileSHA1(fullPath)
+			if err != nil {
+				return err
+			}
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No app files found in directory %s", dir)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// DirectoryHash gives a hash of the files pushed from an app directory, it only depends on paths,
+// types, executable bits and contents of files so that an identical tree always gives the same hash
+// whatever the umask or the modification times of the checkout
+func DirectoryHash(files []AppFile) string {
+	h := sha256.New()
+	for _, f := range files {
+		kind := "f"
+		switch {
+		case f.IsDir():
+			kind = "d"
+		case f.IsSymlink():
+			kind = "l"
+		case f.Mode&0111 != 0:
+			kind = "x"
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00%s\n", f.Path, kind, f.SHA1)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// ZipDirectory writes files of an app directory in a zip, the zip is deterministic:
+// entries are sorted and have a fixed modification time
+func ZipDirectory(dir string, files []AppFile, w io.Writer) error {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		header := &zip.FileHeader{
+			Name:     f.Path,
+			Method:   zip.Deflate,
+			Modified: zipModTime,
+		}
+		header.SetMode(f.Mode)
+		if f.IsDir() {
+			header.Name += "/"
+			header.Method = zip.Store
+		}
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		fullPath := filepath.Join(dir, filepath.FromSlash(f.Path))
+		switch {
+		case f.IsDir():
+			continue
+		case f.IsSymlink():
+			target, err := os.Readlink(fullPath)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(entry, filepath.ToSlash(target))
+			if err != nil {
+				return err
+			}
+		default:
+			err := copyAppFile(fullPath, f, entry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+// ZipDirectoryToTemp zips an app directory in a temporary file, the caller must remove it
+func ZipDirectoryToTemp(dir string, files []AppFile) (string, error) {
+	zipFile, err := os.CreateTemp("", "app-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer zipFile.Close()
+	err = ZipDirectory(dir, files, zipFile)
+	if err != nil {
+		os.Remove(zipFile.Name())
+		return "", err
+	}
+	return zipFile.Name(), nil
+}
+
+func copyAppFile(fullPath string, f AppFile, w io.Writer) error {
+	src, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	sum := sha1.New()
+	_, err = io.Copy(io.MultiWriter(w, sum), src)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprintf("%x", sum.Sum(nil)) != f.SHA1 {
+		return fmt.Errorf("File %s has been modified while pushing app", fullPath)
+	}
+	return nil
+}
+
+func fileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum := sha1.New()
+	_, err = io.Copy(sum, f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sum.Sum(nil)), nil
+}
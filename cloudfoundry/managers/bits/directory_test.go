@@ -0,0 +1,208 @@
+package bits
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestDir creates files of an app directory, a path ending with / is a directory
+func writeTestDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, filepath.FromSlash(path))
+		if path[len(path)-1] == '/' {
+			if err := os.MkdirAll(fullPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func appFilePaths(files []AppFile) []string {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func TestGatherDirectory(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"app.js":                 "app",
+		"lib/util.js":            "util",
+		"manifest.yml":           "applications: []",
+		".gitignore":             "node_modules",
+		".git/config":            "[core]",
+		".DS_Store":              "",
+		"node_modules/dep/a.js":  "dep",
+		"logs/server.log":        "log",
+		"logs/keep.txt":          "keep",
+		"tmp/cache/":             "",
+		"config/secrets.json":    "{}",
+		"config/application.yml": "port: 8080",
+		".cfignore":              "node_modules/\n*.log\ntmp\n/config/secrets.json\n",
+	})
+
+	files, err := GatherDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"app.js", "config", "config/application.yml", "lib", "lib/util.js", "logs", "logs/keep.txt"}
+	if paths := appFilePaths(files); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected files %v, got %v", expected, paths)
+	}
+	for _, f := range files {
+		if f.IsDir() != (f.Path == "config" || f.Path == "lib" || f.Path == "logs") {
+			t.Errorf("unexpected mode %s for %s", f.Mode, f.Path)
+		}
+	}
+}
+
+func TestGatherDirectoryDefaultIgnore(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"index.html":    "<html/>",
+		"manifest.yml":  "applications: []",
+		"manifest.yaml": "applications: []",
+		".svn/entries":  "",
+		".hg/store":     "",
+		"_darcs/prefs":  "",
+		// only the manifest at root is left out, a nested one is part of the app
+		"src/main/resources/manifest.yml": "app: config",
+		"sub/.gitignore":                  "*",
+	})
+
+	files, err := GatherDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"index.html", "manifest.yaml", "src", "src/main", "src/main/resources", "src/main/resources/manifest.yml", "sub"}
+	if paths := appFilePaths(files); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected files %v, got %v", expected, paths)
+	}
+
+	empty := writeTestDir(t, map[string]string{".cfignore": "*", "app.js": "app"})
+	if _, err := GatherDirectory(empty); err == nil {
+		t.Errorf("expected error for directory without app files")
+	}
+}
+
+func TestZipDirectoryDeterministic(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"app.js":      "app",
+		"lib/util.js": "util",
+		"bin/run":     "#!/bin/sh",
+	})
+	if err := os.Chmod(filepath.Join(dir, "bin", "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("app.js", filepath.Join(dir, "main.js")); err != nil {
+		t.Fatal(err)
+	}
+
+	zipDir := func() []byte {
+		files, err := GatherDirectory(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		if err := ZipDirectory(dir, files, buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	first := zipDir()
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "app.js"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if second := zipDir(); !bytes.Equal(first, second) {
+		t.Errorf("zipping the same files twice gave different zips")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(zipModTime) {
+			t.Errorf("%s: unexpected modification time %s", f.Name, f.Modified)
+		}
+		switch f.Name {
+		case "bin/run":
+			if f.Mode().Perm() != 0755 {
+				t.Errorf("%s: executable mode lost, got %s", f.Name, f.Mode())
+			}
+		case "main.js":
+			if f.Mode()&os.ModeSymlink == 0 {
+				t.Errorf("%s: expected a symlink, got %s", f.Name, f.Mode())
+			}
+		}
+	}
+	expected := []string{"app.js", "bin/", "bin/run", "lib/", "lib/util.js", "main.js"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected zip entries %v, got %v", expected, names)
+	}
+}
+
+func TestDirectoryHash(t *testing.T) {
+	dir := writeTestDir(t, map[string]string{
+		"app.js":      "app",
+		"lib/util.js": "util",
+	})
+	hash := func() string {
+		files, err := GatherDirectory(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return DirectoryHash(files)
+	}
+	appPath := filepath.Join(dir, "app.js")
+
+	initial := hash()
+
+	// modification times and permissions other than executable bits do not matter
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(appPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(appPath, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "lib"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if h := hash(); h != initial {
+		t.Errorf("hash changed with modification time or permissions: %s != %s", h, initial)
+	}
+
+	if err := os.Chmod(appPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	executable := hash()
+	if executable == initial {
+		t.Errorf("hash did not change with executable bit")
+	}
+
+	if err := os.WriteFile(appPath, []byte("new app"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if h := hash(); h == executable {
+		t.Errorf("hash did not change with file content")
+	}
+}
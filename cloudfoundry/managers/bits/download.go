@@ -0,0 +1,292 @@
+package bits
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadMaxAttempts is the number of attempts made to download a remote zip before giving up
+const DownloadMaxAttempts = 3
+
+// downloadRetryDelay is waited between download attempts, multiplied by the attempt number
+var downloadRetryDelay = 2 * time.Second
+
+// DownloadOptions authenticate and verify the download of a remote app or buildpack zip
+type DownloadOptions struct {
+	// Headers are added to the download request
+	Headers map[string]string
+	// Username and Password are used for basic authentication
+	Username string
+	Password string
+	// Token is used for bearer authentication
+	Token string
+	// SHA256 is the expected checksum of the zip, it is also checked for local zips
+	SHA256 string
+}
+
+// download is a remote zip downloaded in the cache, done is closed once it is downloaded or failed
+type download struct {
+	done     chan struct{}
+	path     string
+	baseName string
+	sha256   string
+	err      error
+}
+
+// downloadCache keeps zips downloaded during the run so that a zip used by several resources is only downloaded once,
+// downloads are found by their expected checksum, or by their url when no checksum is given
+type downloadCache struct {
+	mu        sync.Mutex
+	dir       string
+	downloads map[string]*download
+}
+
+var downloads = &downloadCache{
+	downloads: make(map[string]*download),
+}
+
+// CleanDownloads removes zips downloaded during the run
+func CleanDownloads() error {
+	downloads.mu.Lock()
+	defer downloads.mu.Unlock()
+	downloads.downloads = make(map[string]*download)
+	if downloads.dir == "" {
+		return nil
+	}
+	dir := downloads.dir
+	downloads.dir = ""
+	return os.RemoveAll(dir)
+}
+
+func checksumKey(sha string) string {
+	return "sha256:" + strings.ToLower(sha)
+}
+
+// get returns the download for the given key, start tells if it must be started by the caller
+func (c *downloadCache) get(key string) (dl *download, start bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if dl, ok := c.downloads[key]; ok {
+		return dl, false
+	}
+	dl = &download{done: make(chan struct{})}
+	c.downloads[key] = dl
+	return dl, true
+}
+
+// finish registers the download under its checksum for next lookups, a failed download is forgotten to be retried
+func (c *downloadCache) finish(key string, dl *download) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if dl.err != nil {
+		delete(c.downloads, key)
+	} else if _, ok := c.downloads[checksumKey(dl.sha256)]; !ok {
+		c.downloads[checksumKey(dl.sha256)] = dl
+	}
+	close(dl.done)
+}
+
+func (c *downloadCache) tempFile() (*os.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		dir, err := ioutil.TempDir("", "cf-downloads-")
+		if err != nil {
+			return nil, err
+		}
+		c.dir = dir
+	}
+	return ioutil.TempFile(c.dir, "download-")
+}
+
+// localZip gives the local path and base name of a zip, remote zips are downloaded in the cache first.
+// The zip checksum is verified when expected checksum is given.
+func (m BitsManager) localZip(zipPath string, opts DownloadOptions) (string, string, error) {
+	if strings.HasPrefix(zipPath, "http") {
+		dl, err := m.download(zipPath, opts)
+		if err != nil {
+			return "", "", err
+		}
+		return dl.path, dl.baseName, nil
+	}
+	zipPath = strings.TrimPrefix(zipPath, "file://")
+	if opts.SHA256 != "" {
+		if IsDirectory(zipPath) {
+			return "", "", fmt.Errorf("checksum can't be verified for directory %s, only for a zip", zipPath)
+		}
+		sha, err := fileSHA256(zipPath)
+		if err != nil {
+			return "", "", err
+		}
+		if err := verifySHA256(zipPath, opts.SHA256, sha); err != nil {
+			return "", "", err
+		}
+	}
+	return zipPath, filepath.Base(zipPath), nil
+}
+
+// download gets a remote zip from the cache or downloads it, concurrent downloads of the same zip are done once
+func (m BitsManager) download(rawURL string, opts DownloadOptions) (*download, error) {
+	key := "url:" + rawURL
+	if opts.SHA256 != "" {
+		key = checksumKey(opts.SHA256)
+	}
+	dl, start := downloads.get(key)
+	if !start {
+		<-dl.done
+		if dl.err != nil {
+			return nil, dl.err
+		}
+		log.Printf("[DEBUG] %s already downloaded, cached zip is used", rawURL)
+		return dl, nil
+	}
+	dl.path, dl.baseName, dl.sha256, dl.err = m.fetch(rawURL, opts)
+	if dl.err == nil {
+		dl.err = verifySHA256(rawURL, opts.SHA256, dl.sha256)
+		if dl.err != nil {
+			os.Remove(dl.path)
+		}
+	}
+	downloads.finish(key, dl)
+	if dl.err != nil {
+		return nil, dl.err
+	}
+	return dl, nil
+}
+
+// fetch downloads a remote zip in the cache directory, an interrupted download is retried
+// and resumed where it stopped when the server supports range requests
+func (m BitsManager) fetch(rawURL string, opts DownloadOptions) (string, string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", err
+	}
+	baseName := path.Base(u.Path)
+
+	f, err := downloads.tempFile()
+	if err != nil {
+		return "", "", "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	var written int64
+	var etag string
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		written, etag, retry, err = m.fetchAttempt(rawURL, opts, f, h, written, etag, &baseName)
+		if err == nil {
+			break
+		}
+		if !retry || attempt >= DownloadMaxAttempts {
+			os.Remove(f.Name())
+			return "", "", "", err
+		}
+		log.Printf("[WARN] download of %s failed after %d bytes (attempt %d/%d), retrying: %s",
+			rawURL, written, attempt, DownloadMaxAttempts, err.Error())
+		time.Sleep(time.Duration(attempt) * downloadRetryDelay)
+	}
+	return f.Name(), baseName, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fetchAttempt downloads the zip from offset written, it gives the number of bytes written so far
+// and whether a failure is worth a retry
+func (m BitsManager) fetchAttempt(rawURL string, opts DownloadOptions, f *os.File, h hash.Hash, written int64, etag string, baseName *string) (int64, string, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return written, etag, false, err
+	}
+	for k, v := range opts.Headers {
+		req.Header.Set(k, v)
+	}
+	if opts.Username != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	} else if opts.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.Token)
+	}
+	if written > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+		if etag != "" {
+			req.Header.Set("If-Range", etag)
+		}
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return written, etag, true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent &&
+		strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", written)):
+		// download is resumed
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		// the whole zip is sent, download starts over
+		if written > 0 {
+			log.Printf("[DEBUG] %s can't be resumed, downloading it again", rawURL)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return written, etag, false, err
+		}
+		if err := f.Truncate(0); err != nil {
+			return written, etag, false, err
+		}
+		h.Reset()
+		written = 0
+		etag = resp.Header.Get("ETag")
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			*baseName = params["filename"]
+		}
+	default:
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode == http.StatusRequestedRangeNotSatisfiable
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			// start over on next attempt
+			written = 0
+		}
+		return written, etag, retry, fmt.Errorf("Failed to download file %s (%s)", rawURL, strings.Trim(resp.Status, " "))
+	}
+
+	n, err := io.Copy(io.MultiWriter(f, h), resp.Body)
+	written += n
+	if err != nil {
+		return written, etag, true, err
+	}
+	return written, etag, false, nil
+}
+
+// verifySHA256 checks a checksum against the expected one, nothing is checked when no checksum is expected
+func verifySHA256(name string, expected string, actual string) error {
+	if expected == "" || strings.EqualFold(expected, actual) {
+		return nil
+	}
+	return fmt.Errorf("checksum mismatch for %s: expected sha256 %s but got %s", name, strings.ToLower(expected), actual)
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,273 @@
+package bits
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testZipServer serves a zip, requests are answered with the given responses in turn, the last one is repeated
+type testZipServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	content   []byte
+	responses []zipResponse
+	requests  []http.Header
+}
+
+type zipResponse struct {
+	// status is sent instead of the zip when set
+	status int
+	// cut closes the connection after half of the zip is sent
+	cut bool
+	// ignoreRange sends the whole zip even to range requests
+	ignoreRange bool
+}
+
+func newTestZipServer(t *testing.T, content []byte, responses ...zipResponse) *testZipServer {
+	srv := &testZipServer{content: content, responses: responses}
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.mu.Lock()
+		srv.requests = append(srv.requests, r.Header.Clone())
+		resp := srv.responses[len(srv.responses)-1]
+		if len(srv.requests) <= len(srv.responses) {
+			resp = srv.responses[len(srv.requests)-1]
+		}
+		srv.mu.Unlock()
+
+		if resp.status != 0 {
+			w.WriteHeader(resp.status)
+			return
+		}
+		w.Header().Set("ETag", `"zip-etag"`)
+		w.Header().Set("Content-Disposition", `attachment; filename="app-v1.zip"`)
+		switch {
+		case resp.cut:
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		case resp.ignoreRange:
+			w.Write(content)
+		default:
+			// range and if-range are honoured
+			http.ServeContent(w, r, "app.zip", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	previousDelay := downloadRetryDelay
+	downloadRetryDelay = 0
+	t.Cleanup(func() {
+		downloadRetryDelay = previousDelay
+		CleanDownloads()
+	})
+	return srv
+}
+
+func (srv *testZipServer) bitsManager() BitsManager {
+	return BitsManager{httpClient: srv.Client()}
+}
+
+func (srv *testZipServer) requestCount() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return len(srv.requests)
+}
+
+func testZipContent() ([]byte, string) {
+	content := bytes.Repeat([]byte("zip content "), 10000)
+	sum := sha256.Sum256(content)
+	return content, hex.EncodeToString(sum[:])
+}
+
+func checkDownload(t *testing.T, dl *download, content []byte, sha string) {
+	t.Helper()
+	downloaded, err := os.ReadFile(dl.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Errorf("downloaded zip differs from the served one (%d bytes instead of %d)", len(downloaded), len(content))
+	}
+	if dl.sha256 != sha {
+		t.Errorf("expected sha256 %s, got %s", sha, dl.sha256)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content, sha := testZipContent()
+	srv := newTestZipServer(t, content, zipResponse{cut: true}, zipResponse{})
+
+	dl, err := srv.bitsManager().download(srv.URL+"/app.zip", DownloadOptions{SHA256: sha})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDownload(t, dl, content, sha)
+	if dl.baseName != "app-v1.zip" {
+		t.Errorf("expected name from content disposition, got %s", dl.baseName)
+	}
+	if len(srv.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(srv.requests))
+	}
+	if srv.requests[0].Get("Range") != "" {
+		t.Errorf("first request must not be a range request, got %s", srv.requests[0].Get("Range"))
+	}
+	if got, expected := srv.requests[1].Get("Range"), fmt.Sprintf("bytes=%d-", len(content)/2); got != expected {
+		t.Errorf("expected download to be resumed with range %s, got %q", expected, got)
+	}
+	if got := srv.requests[1].Get("If-Range"); got != `"zip-etag"` {
+		t.Errorf("expected If-Range with the etag of the zip, got %q", got)
+	}
+}
+
+func TestDownloadRangeIgnored(t *testing.T) {
+	content, sha := testZipContent()
+	srv := newTestZipServer(t, content, zipResponse{cut: true}, zipResponse{ignoreRange: true})
+
+	dl, err := srv.bitsManager().download(srv.URL+"/app.zip", DownloadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// whole zip sent again replaces the part already downloaded
+	checkDownload(t, dl, content, sha)
+	if len(srv.requests) != 2 || srv.requests[1].Get("Range") == "" {
+		t.Errorf("expected a range request after the cut, got %v", srv.requests)
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	content, sha := testZipContent()
+
+	cases := []struct {
+		name      string
+		responses []zipResponse
+		requests  int
+		err       string
+	}{
+		{name: "server error retried", responses: []zipResponse{{status: http.StatusBadGateway}, {}}, requests: 2},
+		{name: "too many requests retried", responses: []zipResponse{{status: http.StatusTooManyRequests}, {}}, requests: 2},
+		{name: "range not satisfiable starts over", responses: []zipResponse{{cut: true}, {status: http.StatusRequestedRangeNotSatisfiable}, {}}, requests: 3},
+		{name: "server error persists", responses: []zipResponse{{status: http.StatusServiceUnavailable}}, requests: DownloadMaxAttempts, err: "503 Service Unavailable"},
+		{name: "not found not retried", responses: []zipResponse{{status: http.StatusNotFound}}, requests: 1, err: "404 Not Found"},
+		{name: "unauthorized not retried", responses: []zipResponse{{status: http.StatusUnauthorized}}, requests: 1, err: "401 Unauthorized"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			srv := newTestZipServer(t, content, c.responses...)
+
+			dl, err := srv.bitsManager().download(srv.URL+"/app.zip", DownloadOptions{})
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q, got %v", c.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else {
+				checkDownload(t, dl, content, sha)
+			}
+			if len(srv.requests) != c.requests {
+				t.Errorf("expected %d requests, got %d", c.requests, len(srv.requests))
+			}
+		})
+	}
+
+	t.Run("range dropped after range not satisfiable", func(t *testing.T) {
+		srv := newTestZipServer(t, content, zipResponse{cut: true}, zipResponse{status: http.StatusRequestedRangeNotSatisfiable}, zipResponse{})
+		if _, err := srv.bitsManager().download(srv.URL+"/app.zip", DownloadOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if srv.requests[1].Get("Range") == "" || srv.requests[2].Get("Range") != "" {
+			t.Errorf("expected download to start over after 416, got ranges %q then %q",
+				srv.requests[1].Get("Range"), srv.requests[2].Get("Range"))
+		}
+	})
+}
+
+func TestDownloadCache(t *testing.T) {
+	content, sha := testZipContent()
+	srv := newTestZipServer(t, content, zipResponse{})
+	m := srv.bitsManager()
+
+	first, err := m.download(srv.URL+"/app.zip", DownloadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := m.download(srv.URL+"/app.zip", DownloadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another url giving the same checksum is the same zip
+	byChecksum, err := m.download(srv.URL+"/mirror/app.zip", DownloadOptions{SHA256: strings.ToUpper(sha)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.requestCount() != 1 {
+		t.Errorf("expected zip to be downloaded once, got %d requests", srv.requestCount())
+	}
+	if again.path != first.path || byChecksum.path != first.path {
+		t.Errorf("expected cached zip %s, got %s and %s", first.path, again.path, byChecksum.path)
+	}
+
+	// a zip not matching its checksum is not kept
+	wrong := strings.Repeat("0", 64)
+	for i := 0; i < 2; i++ {
+		_, err := m.download(srv.URL+"/other.zip", DownloadOptions{SHA256: wrong})
+		if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+			t.Fatalf("expected checksum mismatch, got %v", err)
+		}
+	}
+	if srv.requestCount() != 3 {
+		t.Errorf("expected failed download to be retried, got %d requests", srv.requestCount())
+	}
+
+	if err := CleanDownloads(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(first.path); !os.IsNotExist(err) {
+		t.Errorf("downloaded zip is not removed by CleanDownloads")
+	}
+}
+
+func TestDownloadConcurrent(t *testing.T) {
+	content, sha := testZipContent()
+	srv := newTestZipServer(t, content, zipResponse{})
+	m := srv.bitsManager()
+
+	var wg sync.WaitGroup
+	paths := make([]string, 10)
+	errs := make([]error, 10)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dl, err := m.download(srv.URL+"/app.zip", DownloadOptions{SHA256: sha})
+			errs[i] = err
+			if err == nil {
+				paths[i] = dl.path
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range paths {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Errorf("expected every resource to get the same zip, got %s and %s", paths[0], paths[i])
+		}
+	}
+	if srv.requestCount() != 1 {
+		t.Errorf("expected concurrent downloads of a zip to be done once, got %d requests", srv.requestCount())
+	}
+}
@@ -0,0 +1,238 @@
+package bits
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+)
+
+const (
+	// FeatureFlagResourceMatching is the feature flag enabling resource matching on cloud controller
+	FeatureFlagResourceMatching = "resource_matching"
+
+	// MaxResourceMatchChunkSize is the maximum number of resources sent in one resource match request, as done by cf cli
+	MaxResourceMatchChunkSize = 1000
+)
+
+// appBits are the bits of an app to upload: files already known by cloud controller are given as matched resources
+// and only other files are in the zip
+type appBits struct {
+	matched []ccv3.Resource
+	// zipPath is a zip of files not matched, it is a temporary file removed by cleanup unless it is the app zip itself
+	zipPath string
+	// uploadZip is false when all files have been matched, the zip can then be left out of the upload
+	uploadZip bool
+	cleanup   func()
+}
+
+// prepareAppBits computes checksums of files of an app zip or directory, finds out files cloud controller already
+// has with resource matching and zips the other ones. All files are zipped when resource matching is disabled.
+func (m BitsManager) prepareAppBits(path string) (appBits, error) {
+	path = strings.TrimPrefix(path, "file://")
+	matchingEnabled := m.resourceMatchingEnabled()
+	if IsDirectory(path) {
+		return m.prepareDirectoryBits(path, matchingEnabled)
+	}
+	if !matchingEnabled {
+		return appBits{
+			matched:   make([]ccv3.Resource, 0),
+			zipPath:   path,
+			uploadZip: true,
+			cleanup:   func() {},
+		}, nil
+	}
+	return m.prepareArchiveBits(path)
+}
+
+func (m BitsManager) prepareDirectoryBits(dir string, matchingEnabled bool) (appBits, error) {
+	files, err := GatherDirectory(dir)
+	if err != nil {
+		return appBits{}, err
+	}
+	resources := make([]ccv3.Resource, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() || f.IsSymlink() {
+			continue
+		}
+		resources = append(resources, ccv3.Resource{
+			FilePath:    f.Path,
+			Mode:        f.Mode,
+			Checksum:    ccv3.Checksum{Value: f.SHA1},
+			SizeInBytes: f.Size,
+		})
+	}
+	matched := make([]ccv3.Resource, 0)
+	if matchingEnabled {
+		matched, err = m.matchResources(resources)
+		if err != nil {
+			return appBits{}, err
+		}
+	}
+	matchedPaths := make(map[string]bool)
+	for _, r := range matched {
+		matchedPaths[r.FilePath] = true
+	}
+	newFiles := make([]AppFile, 0, len(files))
+	count := 0
+	for _, f := range files {
+		if matchedPaths[f.Path] {
+			continue
+		}
+		if !f.IsDir() {
+			count++
+		}
+		newFiles = append(newFiles, f)
+	}
+	zipPath, err := ZipDirectoryToTemp(dir, newFiles)
+	if err != nil {
+		return appBits{}, err
+	}
+	return appBits{
+		matched:   matched,
+		zipPath:   zipPath,
+		uploadZip: count > 0,
+		cleanup:   func() { os.Remove(zipPath) },
+	}, nil
+}
+
+func (m BitsManager) prepareArchiveBits(archivePath string) (appBits, error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return appBits{}, err
+	}
+	defer archive.Close()
+
+	resources := make([]ccv3.Resource, 0, len(archive.File))
+	for _, f := range archive.File {
+		if f.FileInfo().IsDir() || f.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		sha, err := zipEntrySHA1(f)
+		if err != nil {
+			return appBits{}, err
+		}
+		resources = append(resources, ccv3.Resource{
+			FilePath:    f.Name,
+			Mode:        f.Mode(),
+			Checksum:    ccv3.Checksum{Value: sha},
+			SizeInBytes: int64(f.UncompressedSize64),
+		})
+	}
+	matched, err := m.matchResources(resources)
+	if err != nil {
+		return appBits{}, err
+	}
+	if len(matched) == 0 {
+		return appBits{
+			matched:   matched,
+			zipPath:   archivePath,
+			uploadZip: true,
+			cleanup:   func() {},
+		}, nil
+	}
+	matchedPaths := make(map[string]bool)
+	for _, r := range matched {
+		matchedPaths[r.FilePath] = true
+	}
+
+	zipFile, err := os.CreateTemp("", "app-*.zip")
+	if err != nil {
+		return appBits{}, err
+	}
+	defer zipFile.Close()
+	cleanup := func() { os.Remove(zipFile.Name()) }
+	zw := zip.NewWriter(zipFile)
+	count := 0
+	for _, f := range archive.File {
+		if matchedPaths[f.Name] {
+			continue
+		}
+		if !f.FileInfo().IsDir() {
+			count++
+		}
+		// entries are copied without being decompressed
+		err := zw.Copy(f)
+		if err != nil {
+			cleanup()
+			return appBits{}, err
+		}
+	}
+	err = zw.Close()
+	if err != nil {
+		cleanup()
+		return appBits{}, err
+	}
+	return appBits{
+		matched:   matched,
+		zipPath:   zipFile.Name(),
+		uploadZip: count > 0,
+		cleanup:   cleanup,
+	}, nil
+}
+
+// resourceMatchingEnabled checks the resource_matching feature flag, resource matching is
+// considered enabled when the flag can't be read as cloud controller ignores it when disabled
+func (m BitsManager) resourceMatchingEnabled() bool {
+	flag, _, err := m.clientV3.GetFeatureFlag(FeatureFlagResourceMatching)
+	if err != nil {
+		log.Printf("[WARN] feature flag %s can't be read, resource matching is used: %s", FeatureFlagResourceMatching, err.Error())
+		return true
+	}
+	return flag.Enabled
+}
+
+// matchResources gives resources cloud controller already has, empty files are never matched
+func (m BitsManager) matchResources(resources []ccv3.Resource) ([]ccv3.Resource, error) {
+	candidates := make([]ccv3.Resource, 0, len(resources))
+	for _, r := range resources {
+		if r.SizeInBytes > 0 {
+			candidates = append(candidates, r)
+		}
+	}
+	known := make(map[string]bool)
+	for start := 0; start < len(candidates); start += MaxResourceMatchChunkSize {
+		end := start + MaxResourceMatchChunkSize
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		chunkMatched, _, err := m.clientV3.ResourceMatch(candidates[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range chunkMatched {
+			known[resourceKey(r)] = true
+		}
+	}
+	matched := make([]ccv3.Resource, 0, len(known))
+	for _, r := range candidates {
+		if known[resourceKey(r)] {
+			matched = append(matched, r)
+		}
+	}
+	log.Printf("[DEBUG] %d files out of %d already known by cloud controller are not uploaded", len(matched), len(resources))
+	return matched, nil
+}
+
+func resourceKey(r ccv3.Resource) string {
+	return fmt.Sprintf("%s:%d", r.Checksum.Value, r.SizeInBytes)
+}
+
+func zipEntrySHA1(f *zip.File) (string, error) {
+	r, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	sum := sha1.New()
+	_, err = io.Copy(sum, r)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sum.Sum(nil)), nil
+}
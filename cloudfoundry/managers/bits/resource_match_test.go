@@ -0,0 +1,374 @@
+package bits
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
+)
+
+// matchCC is a cloud controller stand-in doing resource matching against known file contents
+// and recording resource match requests and bits uploads
+type matchCC struct {
+	*httptest.Server
+	mu sync.Mutex
+	// matchingEnabled is the value of the resource_matching feature flag
+	matchingEnabled bool
+	// known are the sha1 of files cloud controller already has
+	known map[string]bool
+	// matchRequests are the resources sent by each resource match request
+	matchRequests [][]matchResource
+	uploads       []testUpload
+}
+
+type matchResource struct {
+	Path     string `json:"path"`
+	Mode     string `json:"mode"`
+	Checksum struct {
+		Value string `json:"value"`
+	} `json:"checksum"`
+	SizeInBytes int64 `json:"size_in_bytes"`
+}
+
+func newMatchCC(t *testing.T, matchingEnabled bool, knownContents ...string) *matchCC {
+	cc := &matchCC{matchingEnabled: matchingEnabled, known: make(map[string]bool)}
+	for _, c := range knownContents {
+		cc.known[sha1Hex(c)] = true
+	}
+	cc.Server = httptest.NewServer(http.HandlerFunc(cc.serve(t)))
+	t.Cleanup(cc.Close)
+	return cc
+}
+
+func (cc *matchCC) serve(t *testing.T) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			fmt.Fprintf(w, `{"links":{"cloud_controller_v3":{"href":"%s/v3"}}}`, cc.URL)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3":
+			fmt.Fprintf(w, `{"links":{"feature_flags":{"href":"%[1]s/v3/feature_flags"},"packages":{"href":"%[1]s/v3/packages"},"resource_matches":{"href":"%[1]s/v3/resource_matches"}}}`, cc.URL)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/feature_flags/"+FeatureFlagResourceMatching:
+			fmt.Fprintf(w, `{"name":"%s","enabled":%t}`, FeatureFlagResourceMatching, cc.matchingEnabled)
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/resource_matches":
+			var body struct {
+				Resources []matchResource `json:"resources"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("invalid resource match request: %s", err)
+			}
+			matched := make([]matchResource, 0)
+			for _, res := range body.Resources {
+				if cc.known[res.Checksum.Value] {
+					matched = append(matched, res)
+				}
+			}
+			cc.mu.Lock()
+			cc.matchRequests = append(cc.matchRequests, body.Resources)
+			cc.mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string][]matchResource{"resources": matched})
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/packages":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"guid":"package-guid","type":"bits","state":"AWAITING_UPLOAD"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/packages/package-guid":
+			fmt.Fprint(w, `{"guid":"package-guid","type":"bits","state":"READY"}`)
+		case r.URL.Path == "/v3/packages/package-guid/upload" || r.URL.Path == "/v2/apps/app-guid/bits":
+			upload := testUpload{
+				method: r.Method,
+				path:   r.URL.Path,
+				fields: make(map[string]string),
+				files:  make(map[string][]byte),
+			}
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				upload.err = err
+			} else {
+				for k, v := range r.MultipartForm.Value {
+					upload.fields[k] = v[0]
+				}
+				for k, v := range r.MultipartForm.File {
+					f, _ := v[0].Open()
+					upload.files[k], _ = ioutil.ReadAll(f)
+					f.Close()
+				}
+			}
+			cc.mu.Lock()
+			cc.uploads = append(cc.uploads, upload)
+			cc.mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"guid":"package-guid","type":"bits","state":"PROCESSING_UPLOAD"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func (cc *matchCC) bitsManager(t *testing.T) BitsManager {
+	clientV3 := ccv3.NewClient(ccv3.Config{AppName: "test", AppVersion: "test"})
+	if _, _, err := clientV3.TargetCF(ccv3.TargetSettings{URL: cc.URL}); err != nil {
+		t.Fatal(err)
+	}
+	return BitsManager{
+		clientV3:  clientV3,
+		rawClient: raw.NewRawClient(raw.RawClientConfig{ApiEndpoint: cc.URL}),
+	}
+}
+
+func sha1Hex(content string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(content)))
+}
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeTestArchive(t *testing.T, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "app.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// zipEntries gives the names of files in a zip, directories excluded
+func zipEntries(t *testing.T, path string) []string {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	names := make([]string, 0)
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchedPaths(resources []ccv3.Resource) []string {
+	paths := make([]string, 0, len(resources))
+	for _, r := range resources {
+		paths = append(paths, r.FilePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestMatchResourcesChunks(t *testing.T) {
+	cc := newMatchCC(t, true, "file-1", "file-2500")
+	resources := make([]ccv3.Resource, 0, 2501)
+	for i := 1; i <= 2500; i++ {
+		content := fmt.Sprintf("file-%d", i)
+		resources = append(resources, ccv3.Resource{
+			FilePath:    content,
+			Mode:        0644,
+			Checksum:    ccv3.Checksum{Value: sha1Hex(content)},
+			SizeInBytes: int64(len(content)),
+		})
+	}
+	resources = append(resources, ccv3.Resource{
+		FilePath: "empty",
+		Mode:     0644,
+		Checksum: ccv3.Checksum{Value: sha1Hex("")},
+	})
+
+	matched, err := cc.bitsManager(t).matchResources(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(matchedPaths(matched), ","); got != "file-1,file-2500" {
+		t.Errorf("unexpected matched resources %s", got)
+	}
+	if len(cc.matchRequests) != 3 {
+		t.Fatalf("expected 3 resource match requests, got %d", len(cc.matchRequests))
+	}
+	for i, size := range []int{1000, 1000, 500} {
+		if len(cc.matchRequests[i]) != size {
+			t.Errorf("expected %d resources in request %d, got %d", size, i, len(cc.matchRequests[i]))
+		}
+		for _, r := range cc.matchRequests[i] {
+			if r.SizeInBytes == 0 {
+				t.Errorf("empty file %s sent to resource matching", r.Path)
+			}
+		}
+	}
+}
+
+func TestPrepareDirectoryBits(t *testing.T) {
+	cc := newMatchCC(t, true, "known content", "")
+	dir := writeTestFiles(t, map[string]string{
+		"known.txt":     "known content",
+		"lib/known.txt": "known content",
+		"new.txt":       "new content",
+		"empty.txt":     "",
+	})
+
+	bits, err := cc.bitsManager(t).prepareDirectoryBits(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bits.cleanup()
+	if got := strings.Join(matchedPaths(bits.matched), ","); got != "known.txt,lib/known.txt" {
+		t.Errorf("unexpected matched resources %s", got)
+	}
+	if got := strings.Join(zipEntries(t, bits.zipPath), ","); got != "empty.txt,new.txt" {
+		t.Errorf("unexpected zipped files %s", got)
+	}
+	if !bits.uploadZip {
+		t.Errorf("zip with new files must be uploaded")
+	}
+	for _, r := range cc.matchRequests[0] {
+		if r.Path == "empty.txt" {
+			t.Errorf("empty file sent to resource matching")
+		}
+	}
+}
+
+func TestPrepareArchiveBits(t *testing.T) {
+	cc := newMatchCC(t, true, "known content")
+	archivePath := writeTestArchive(t, map[string]string{
+		"known.txt": "known content",
+		"lib/":      "",
+		"new.txt":   "new content",
+	})
+
+	bits, err := cc.bitsManager(t).prepareArchiveBits(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bits.cleanup()
+	if got := strings.Join(matchedPaths(bits.matched), ","); got != "known.txt" {
+		t.Errorf("unexpected matched resources %s", got)
+	}
+	if bits.zipPath == archivePath {
+		t.Fatalf("archive with matched files must be rewritten")
+	}
+	if got := strings.Join(zipEntries(t, bits.zipPath), ","); got != "new.txt" {
+		t.Errorf("unexpected zipped files %s", got)
+	}
+	if !bits.uploadZip {
+		t.Errorf("zip with new files must be uploaded")
+	}
+	bits.cleanup()
+	if _, err := os.Stat(bits.zipPath); !os.IsNotExist(err) {
+		t.Errorf("rewritten zip is not removed by cleanup")
+	}
+}
+
+func TestPrepareAppBitsMatchingDisabled(t *testing.T) {
+	files := map[string]string{"known.txt": "known content", "new.txt": "new content"}
+	cc := newMatchCC(t, false, "known content")
+	m := cc.bitsManager(t)
+
+	dirBits, err := m.prepareAppBits(writeTestFiles(t, files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dirBits.cleanup()
+	if got := strings.Join(zipEntries(t, dirBits.zipPath), ","); got != "known.txt,new.txt" || len(dirBits.matched) != 0 {
+		t.Errorf("all files must be zipped, got %s and %d matched", got, len(dirBits.matched))
+	}
+
+	archivePath := writeTestArchive(t, files)
+	archiveBits, err := m.prepareAppBits(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archiveBits.cleanup()
+	if archiveBits.zipPath != archivePath || !archiveBits.uploadZip || len(archiveBits.matched) != 0 {
+		t.Errorf("archive must be uploaded as is, got %+v", archiveBits)
+	}
+
+	if len(cc.matchRequests) != 0 {
+		t.Errorf("resource matching is disabled but %d resource match requests were sent", len(cc.matchRequests))
+	}
+}
+
+func TestUploadFullyMatched(t *testing.T) {
+	files := map[string]string{"known.txt": "known content", "lib/known.txt": "known content"}
+
+	t.Run("v3", func(t *testing.T) {
+		cc := newMatchCC(t, true, "known content")
+		_, _, err := cc.bitsManager(t).CreateAndUploadBitsPackage("app-guid", writeTestFiles(t, files), DownloadOptions{}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cc.uploads) != 1 {
+			t.Fatalf("expected 1 upload, got %d", len(cc.uploads))
+		}
+		upload := cc.uploads[0]
+		if upload.err != nil {
+			t.Fatal(upload.err)
+		}
+		if _, ok := upload.files["bits"]; ok {
+			t.Errorf("zip uploaded while all files are matched")
+		}
+		var matched []matchResource
+		if err := json.Unmarshal([]byte(upload.fields["resources"]), &matched); err != nil || len(matched) != 2 {
+			t.Errorf("expected 2 matched resources, got %s", upload.fields["resources"])
+		}
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		cc := newMatchCC(t, true, "known content")
+		err := cc.bitsManager(t).UploadApp("app-guid", writeTestFiles(t, files), DownloadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cc.uploads) != 1 {
+			t.Fatalf("expected 1 upload, got %d", len(cc.uploads))
+		}
+		upload := cc.uploads[0]
+		if upload.err != nil {
+			t.Fatal(upload.err)
+		}
+		if _, ok := upload.files["application"]; ok {
+			t.Errorf("zip uploaded while all files are matched")
+		}
+		var matched []map[string]interface{}
+		if err := json.Unmarshal([]byte(upload.fields["resources"]), &matched); err != nil || len(matched) != 2 {
+			t.Errorf("expected 2 matched resources, got %s", upload.fields["resources"])
+		}
+	})
+}
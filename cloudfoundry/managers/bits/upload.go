@@ -0,0 +1,214 @@
+package bits
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
+)
+
+// UploadMaxAttempts is the number of attempts made to upload bits when the file uploaded can be read again
+const UploadMaxAttempts = 3
+
+// uploadRetryDelay is waited between upload attempts, multiplied by the attempt number
+var uploadRetryDelay = 2 * time.Second
+
+// formField is a form field sent before the file of a multipart upload
+type formField struct {
+	name  string
+	value []byte
+}
+
+// uploadFile is the file part of a multipart upload, it can be uploaded again on failure when r is an io.Seeker.
+// A zero uploadFile sends no file part.
+type uploadFile struct {
+	fieldName string
+	fileName  string
+	// contentType of the file part, application/zip when not set
+	contentType string
+	r           io.Reader
+	size        int64
+}
+
+// multipartUpload is a multipart body made of form fields and a file, multipart headers are built beforehand
+// so that the body is streamed with a known length and errors reading the file are returned by the request
+type multipartUpload struct {
+	contentType string
+	prefix      []byte
+	suffix      []byte
+	file        uploadFile
+}
+
+func newMultipartUpload(fields []formField, file uploadFile) (*multipartUpload, error) {
+	buf := new(bytes.Buffer)
+	mpw := multipart.NewWriter(buf)
+	for _, f := range fields {
+		part, err := mpw.CreateFormField(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(f.value); err != nil {
+			return nil, err
+		}
+	}
+	if file.r != nil {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, file.fieldName, file.fileName))
+		contentType := file.contentType
+		if contentType == "" {
+			contentType = "application/zip"
+		}
+		h.Set("Content-Type", contentType)
+		h.Set("Content-Length", fmt.Sprintf("%d", file.size))
+		h.Set("Content-Transfer-Encoding", "binary")
+		if _, err := mpw.CreatePart(h); err != nil {
+			return nil, err
+		}
+	}
+	prefix := append([]byte(nil), buf.Bytes()...)
+	buf.Reset()
+	if err := mpw.Close(); err != nil {
+		return nil, err
+	}
+	return &multipartUpload{
+		contentType: mpw.FormDataContentType(),
+		prefix:      prefix,
+		suffix:      append([]byte(nil), buf.Bytes()...),
+		file:        file,
+	}, nil
+}
+
+func (u *multipartUpload) contentLength() int64 {
+	return int64(len(u.prefix)) + u.file.size + int64(len(u.suffix))
+}
+
+func (u *multipartUpload) replayable() bool {
+	if u.file.r == nil {
+		return true
+	}
+	_, ok := u.file.r.(io.Seeker)
+	return ok
+}
+
+// body gives the multipart body from the start of the file, the file reader keeps errors met when reading the file
+func (u *multipartUpload) body() (io.Reader, *fileReader, error) {
+	if s, ok := u.file.r.(io.Seeker); ok {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, err
+		}
+	}
+	fr := &fileReader{r: bytes.NewReader(nil), file: u.file}
+	if u.file.r != nil {
+		fr.r = io.LimitReader(u.file.r, u.file.size)
+	}
+	return io.MultiReader(bytes.NewReader(u.prefix), fr, bytes.NewReader(u.suffix)), fr, nil
+}
+
+// fileReader reads the file of an upload, a file shorter than announced is an error
+type fileReader struct {
+	r    io.Reader
+	file uploadFile
+	read int64
+	err  error
+}
+
+func (f *fileReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.read += int64(n)
+	if err == io.EOF && f.read < f.file.size {
+		err = fmt.Errorf("%s is %d bytes long instead of %d", f.file.fileName, f.read, f.file.size)
+	}
+	if err != nil && err != io.EOF {
+		f.err = fmt.Errorf("Error reading %s for upload: %s", f.file.fileName, err.Error())
+	}
+	return n, err
+}
+
+// upload sends a multipart upload to cloud controller and gives response headers, a failed upload is retried
+// when the file can be read again. Responses not in 2xx are returned as cloud controller errors.
+func (m BitsManager) upload(method string, apiURL string, u *multipartUpload) (http.Header, error) {
+	for attempt := 1; ; attempt++ {
+		header, retry, err := m.uploadAttempt(method, apiURL, u)
+		if err == nil {
+			return header, nil
+		}
+		if !retry || !u.replayable() || attempt >= UploadMaxAttempts {
+			return nil, err
+		}
+		log.Printf("[WARN] upload to %s failed (attempt %d/%d), retrying: %s", apiURL, attempt, UploadMaxAttempts, err.Error())
+		time.Sleep(time.Duration(attempt) * uploadRetryDelay)
+	}
+}
+
+// uploadAttempt sends the upload once and tells whether a failure is worth a retry
+func (m BitsManager) uploadAttempt(method string, apiURL string, u *multipartUpload) (http.Header, bool, error) {
+	body, fr, err := u.body()
+	if err != nil {
+		return nil, false, err
+	}
+	req, err := m.rawClient.NewRequest(method, apiURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", u.contentType)
+	req.ContentLength = u.contentLength()
+	req.Body = ioutil.NopCloser(body)
+
+	resp, err := m.rawClient.Do(req)
+	if fr.err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, false, fr.err
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.Header, false, nil
+	}
+	retry := resp.StatusCode == http.StatusInternalServerError ||
+		resp.StatusCode == http.StatusBadGateway ||
+		resp.StatusCode == http.StatusServiceUnavailable ||
+		resp.StatusCode == http.StatusGatewayTimeout
+	return nil, retry, uploadResponseError(resp, b)
+}
+
+// uploadResponseError gives the cloud controller error of a failed upload, v3 and v2 error bodies are handled
+func uploadResponseError(resp *http.Response, body []byte) error {
+	requestIDs := resp.Header.Values("X-Vcap-Request-Id")
+	var v3Err ccerror.V3ErrorResponse
+	if json.Unmarshal(body, &v3Err) == nil && len(v3Err.Errors) > 0 {
+		return ccerror.V3UnexpectedResponseError{
+			V3ErrorResponse: v3Err,
+			ResponseCode:    resp.StatusCode,
+			RequestIDs:      requestIDs,
+		}
+	}
+	var v2Err ccerror.V2ErrorResponse
+	if json.Unmarshal(body, &v2Err) == nil && v2Err.Description != "" {
+		return ccerror.V2UnexpectedResponseError{
+			V2ErrorResponse: v2Err,
+			ResponseCode:    resp.StatusCode,
+			RequestIDs:      requestIDs,
+		}
+	}
+	return ccerror.RawHTTPStatusError{
+		StatusCode:  resp.StatusCode,
+		RawResponse: body,
+		RequestIDs:  requestIDs,
+	}
+}
@@ -0,0 +1,315 @@
+package bits
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
+)
+
+// testCC is a cloud controller stand-in answering uploads with the given responses in turn, the last one is repeated
+type testCC struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []testResponse
+	uploads   []testUpload
+}
+
+type testResponse struct {
+	status int
+	body   string
+	// hangUp closes the connection without response
+	hangUp bool
+}
+
+type testUpload struct {
+	method string
+	path   string
+	fields map[string]string
+	files  map[string][]byte
+	// contentTypes are the content types of the file parts
+	contentTypes map[string]string
+	// err is set when the upload is not a valid multipart body, e.g. when aborted by the client
+	err error
+}
+
+func newTestCC(t *testing.T, responses ...testResponse) *testCC {
+	cc := &testCC{responses: responses}
+	cc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upload := testUpload{
+			method:       r.Method,
+			path:         r.URL.Path,
+			fields:       make(map[string]string),
+			files:        make(map[string][]byte),
+			contentTypes: make(map[string]string),
+		}
+		if r.ContentLength < 0 {
+			t.Errorf("upload content length is not known")
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			upload.err = err
+		} else {
+			for k, v := range r.MultipartForm.Value {
+				upload.fields[k] = v[0]
+			}
+			for k, v := range r.MultipartForm.File {
+				f, _ := v[0].Open()
+				upload.files[k], _ = ioutil.ReadAll(f)
+				upload.contentTypes[k] = v[0].Header.Get("Content-Type")
+				f.Close()
+			}
+		}
+
+		cc.mu.Lock()
+		cc.uploads = append(cc.uploads, upload)
+		resp := cc.responses[len(cc.responses)-1]
+		if len(cc.uploads) <= len(cc.responses) {
+			resp = cc.responses[len(cc.uploads)-1]
+		}
+		cc.mu.Unlock()
+
+		if resp.hangUp {
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("X-Vcap-Request-Id", "request-id")
+		w.WriteHeader(resp.status)
+		fmt.Fprint(w, resp.body)
+	}))
+	t.Cleanup(cc.Close)
+
+	previousDelay := uploadRetryDelay
+	uploadRetryDelay = 0
+	t.Cleanup(func() { uploadRetryDelay = previousDelay })
+	return cc
+}
+
+func (cc *testCC) bitsManager() BitsManager {
+	return BitsManager{
+		rawClient: raw.NewRawClient(raw.RawClientConfig{ApiEndpoint: cc.URL}),
+	}
+}
+
+func writeTestZip(t *testing.T, content []byte) string {
+	path := filepath.Join(t.TempDir(), "buildpack.zip")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// failingReader fails after giving some bytes, like a broken file or connection
+type failingReader struct {
+	data []byte
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, errors.New("disk failure")
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestUploadBuildpack(t *testing.T) {
+	content := bytes.Repeat([]byte("buildpack"), 10000)
+
+	cases := []struct {
+		lifecycle   string
+		fileName    string
+		contentType string
+	}{
+		{lifecycle: "buildpack", fileName: "buildpack.zip", contentType: "application/zip"},
+		{lifecycle: "cnb", fileName: "buildpack.cnb", contentType: "application/x-tar"},
+		{lifecycle: "cnb", fileName: "buildpack.tgz", contentType: "application/gzip"},
+	}
+	for _, c := range cases {
+		t.Run(c.fileName, func(t *testing.T) {
+			cc := newTestCC(t, testResponse{status: http.StatusOK, body: `{}`})
+			path := filepath.Join(t.TempDir(), c.fileName)
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			err := cc.bitsManager().UploadBuildpack("bp-guid", path, c.lifecycle, DownloadOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cc.uploads) != 1 {
+				t.Fatalf("expected 1 upload, got %d", len(cc.uploads))
+			}
+			upload := cc.uploads[0]
+			if upload.method != http.MethodPost || upload.path != "/v3/buildpacks/bp-guid/upload" {
+				t.Errorf("unexpected upload %s %s", upload.method, upload.path)
+			}
+			if !bytes.Equal(upload.files["bits"], content) {
+				t.Errorf("uploaded buildpack differs from the file")
+			}
+			if upload.contentTypes["bits"] != c.contentType {
+				t.Errorf("expected content type %s, got %s", c.contentType, upload.contentTypes["bits"])
+			}
+		})
+	}
+}
+
+func TestUploadFields(t *testing.T) {
+	content := []byte("application zip")
+	cc := newTestCC(t, testResponse{status: http.StatusCreated, body: `{}`})
+
+	upload, err := newMultipartUpload([]formField{{name: "resources", value: []byte(`[{"fn":"a"}]`)}}, uploadFile{
+		fieldName: "application",
+		fileName:  "application.zip",
+		r:         bytes.NewReader(content),
+		size:      int64(len(content)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cc.bitsManager().upload(http.MethodPut, "/v2/apps/app-guid/bits", upload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields := cc.uploads[0].fields; fields["resources"] != `[{"fn":"a"}]` {
+		t.Errorf("unexpected fields %v", fields)
+	}
+	if !bytes.Equal(cc.uploads[0].files["application"], content) {
+		t.Errorf("uploaded app differs from the zip")
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	content := []byte("some zip content")
+
+	cases := []struct {
+		name      string
+		responses []testResponse
+		reader    func() io.Reader
+		size      int64
+		uploads   int
+		err       string
+		errType   error
+	}{
+		{
+			name:      "v3 error body",
+			responses: []testResponse{{status: http.StatusUnprocessableEntity, body: `{"errors":[{"code":290008,"title":"CF-BuildpackZipError","detail":"Buildpack zip error"}]}`}},
+			uploads:   1,
+			err:       "Buildpack zip error",
+			errType:   ccerror.V3UnexpectedResponseError{},
+		},
+		{
+			name:      "v2 error body",
+			responses: []testResponse{{status: http.StatusBadRequest, body: `{"code":160001,"description":"The app upload is invalid","error_code":"CF-AppBitsUploadInvalid"}`}},
+			uploads:   1,
+			err:       "The app upload is invalid",
+			errType:   ccerror.V2UnexpectedResponseError{},
+		},
+		{
+			name:      "unknown error body",
+			responses: []testResponse{{status: http.StatusForbidden, body: `forbidden`}},
+			uploads:   1,
+			err:       "Error Code: 403",
+			errType:   ccerror.RawHTTPStatusError{},
+		},
+		{
+			name:      "server errors retried until giving up",
+			responses: []testResponse{{status: http.StatusBadGateway, body: `bad gateway`}},
+			uploads:   UploadMaxAttempts,
+			err:       "Error Code: 502",
+		},
+		{
+			name:      "server error retried",
+			responses: []testResponse{{status: http.StatusServiceUnavailable}, {status: http.StatusOK, body: `{}`}},
+			uploads:   2,
+		},
+		{
+			name:      "connection failure retried",
+			responses: []testResponse{{hangUp: true}, {status: http.StatusOK, body: `{}`}},
+			uploads:   2,
+		},
+		{
+			name:      "server error not retried when file can't be read again",
+			responses: []testResponse{{status: http.StatusServiceUnavailable, body: `unavailable`}},
+			reader:    func() io.Reader { return io.MultiReader(bytes.NewReader(content)) },
+			uploads:   1,
+			err:       "Error Code: 503",
+		},
+		{
+			name:      "file read failure",
+			responses: []testResponse{{status: http.StatusOK, body: `{}`}},
+			reader:    func() io.Reader { return &failingReader{data: content[:4]} },
+			err:       "Error reading bits.zip for upload: disk failure",
+		},
+		{
+			name:      "file shorter than announced",
+			responses: []testResponse{{status: http.StatusOK, body: `{}`}},
+			size:      int64(len(content)) + 10,
+			err:       fmt.Sprintf("bits.zip is %d bytes long instead of %d", len(content), len(content)+10),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cc := newTestCC(t, c.responses...)
+			var r io.Reader = bytes.NewReader(content)
+			if c.reader != nil {
+				r = c.reader()
+			}
+			size := int64(len(content))
+			if c.size != 0 {
+				size = c.size
+			}
+			upload, err := newMultipartUpload(nil, uploadFile{fieldName: "bits", fileName: "bits.zip", r: r, size: size})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cc.bitsManager().upload(http.MethodPost, "/v3/buildpacks/bp-guid/upload", upload)
+
+			if c.err == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+			if c.errType != nil && fmt.Sprintf("%T", err) != fmt.Sprintf("%T", c.errType) {
+				t.Errorf("expected error of type %T, got %T", c.errType, err)
+			}
+			if c.uploads != 0 && len(cc.uploads) != c.uploads {
+				t.Errorf("expected %d uploads, got %d", c.uploads, len(cc.uploads))
+			}
+			// retried uploads must send the whole zip again
+			if c.err == "" && cc.uploads[len(cc.uploads)-1].err != nil {
+				t.Errorf("invalid multipart upload: %s", cc.uploads[len(cc.uploads)-1].err)
+			}
+			if c.err == "" && !bytes.Equal(cc.uploads[len(cc.uploads)-1].files["bits"], content) {
+				t.Errorf("uploaded bits differ from the zip")
+			}
+		})
+	}
+}
+
+func TestUploadErrorRequestIDs(t *testing.T) {
+	cc := newTestCC(t, testResponse{status: http.StatusUnprocessableEntity, body: `{"errors":[{"code":10008,"title":"CF-UnprocessableEntity","detail":"invalid"}]}`})
+	upload, err := newMultipartUpload(nil, uploadFile{fieldName: "bits", fileName: "bits.zip", r: strings.NewReader("zip"), size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cc.bitsManager().upload(http.MethodPost, "/v3/buildpacks/bp-guid/upload", upload)
+	var ccErr ccerror.V3UnexpectedResponseError
+	if !errors.As(err, &ccErr) {
+		t.Fatalf("expected cloud controller error, got %v", err)
+	}
+	if ccErr.ResponseCode != http.StatusUnprocessableEntity || len(ccErr.RequestIDs) != 1 || ccErr.RequestIDs[0] != "request-id" {
+		t.Errorf("unexpected error %+v", ccErr)
+	}
+}
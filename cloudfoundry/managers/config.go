@@ -13,6 +13,7 @@ type Config struct {
 	UaaClientSecret           string
 	SkipSslValidation         bool
 	AppLogsMax                int
+	AppLogsDir                string
 	PurgeWhenDelete           bool
 	DefaultQuotaName          string
 	StoreTokensPath           string
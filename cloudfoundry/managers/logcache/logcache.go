@@ -0,0 +1,265 @@
+package logcache
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
+)
+
+// MaxLimit is the maximum number of envelopes log cache gives for one read
+const MaxLimit = 1000
+
+// StreamInterval is the interval between two reads when streaming logs, log cache has no streaming api
+const StreamInterval = 2 * time.Second
+
+// ReadTimeout is the maximum duration of a read, logs are best effort and must never hang a deployment
+const ReadTimeout = 30 * time.Second
+
+// streamStopTimeout is the maximum duration stopping a stream waits for the last read
+var streamStopTimeout = 5 * time.Second
+
+const (
+	EnvelopeTypeLog     = "LOG"
+	EnvelopeTypeCounter = "COUNTER"
+	EnvelopeTypeGauge   = "GAUGE"
+	EnvelopeTypeTimer   = "TIMER"
+	EnvelopeTypeEvent   = "EVENT"
+)
+
+type LogCacheTokenStore interface {
+	AccessToken() string
+}
+
+// LogCacheClient reads app logs from log cache, it replaces traffic controller on recent foundations
+type LogCacheClient struct {
+	httpClient  *http.Client
+	endpoint    string
+	store       LogCacheTokenStore
+	maxMessages int
+}
+
+// ReadOptions filters envelopes read from log cache, zero values are not sent
+type ReadOptions struct {
+	EnvelopeTypes []string
+	StartTime     time.Time
+	EndTime       time.Time
+	Limit         int
+	Descending    bool
+}
+
+// Envelope is a loggregator envelope as given by log cache json api
+type Envelope struct {
+	Timestamp  json.Number       `json:"timestamp"`
+	SourceID   string            `json:"source_id"`
+	InstanceID string            `json:"instance_id"`
+	Tags       map[string]string `json:"tags"`
+	Log        *Log              `json:"log"`
+}
+
+type Log struct {
+	Payload []byte `json:"payload"`
+	Type    string `json:"type"`
+}
+
+// Time returns the time the envelope has been emitted
+func (e Envelope) Time() time.Time {
+	ns, _ := strconv.ParseInt(e.Timestamp.String(), 10, 64)
+	return time.Unix(0, ns)
+}
+
+func NewLogCacheClient(logCacheUrl string, skipSslValidation bool, dialTimeout time.Duration, store LogCacheTokenStore, maxMessages int) *LogCacheClient {
+	return &LogCacheClient{
+		httpClient: &http.Client{
+			Timeout: ReadTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: skipSslValidation,
+				},
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					KeepAlive: 30 * time.Second,
+					Timeout:   dialTimeout,
+				}).DialContext,
+			},
+		},
+		endpoint:    strings.TrimSuffix(logCacheUrl, "/"),
+		store:       store,
+		maxMessages: maxMessages,
+	}
+}
+
+// Read returns envelopes of the source (an app guid for app logs) matching options
+func (c LogCacheClient) Read(sourceID string, opts ReadOptions) ([]Envelope, error) {
+	return c.read(context.Background(), sourceID, opts)
+}
+
+func (c LogCacheClient) read(ctx context.Context, sourceID string, opts ReadOptions) ([]Envelope, error) {
+	query := url.Values{}
+	for _, t := range opts.EnvelopeTypes {
+		query.Add("envelope_types", t)
+	}
+	if !opts.StartTime.IsZero() {
+		query.Set("start_time", strconv.FormatInt(opts.StartTime.UnixNano(), 10))
+	}
+	if !opts.EndTime.IsZero() {
+		query.Set("end_time", strconv.FormatInt(opts.EndTime.UnixNano(), 10))
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Descending {
+		query.Set("descending", "true")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/api/v1/read/%s?%s", c.endpoint, url.PathEscape(sourceID), query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", c.store.AccessToken())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("log cache responded with status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var result struct {
+		Envelopes struct {
+			Batch []Envelope `json:"batch"`
+		} `json:"envelopes"`
+	}
+	err = json.Unmarshal(b, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Envelopes.Batch, nil
+}
+
+// RecentLogs returns the last logs of an app within the limit of max messages, formatted like NOAAClient does
+func (c LogCacheClient) RecentLogs(appGUID string) (string, error) {
+	limit := c.maxMessages
+	if limit < 0 || limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if limit == 0 {
+		return "", nil
+	}
+	envelopes, err := c.Read(appGUID, ReadOptions{
+		EnvelopeTypes: []string{EnvelopeTypeLog},
+		Limit:         limit,
+		Descending:    true,
+	})
+	if err != nil {
+		return "", err
+	}
+	logs := ""
+	// envelopes are given from the most recent one
+	for i := len(envelopes) - 1; i >= 0; i-- {
+		logs += FormatEnvelope(envelopes[i])
+	}
+	return logs, nil
+}
+
+// StreamLogs polls log cache for logs of an app emitted from now until stop is called,
+// a last read is done when stopping to give logs emitted since the previous poll.
+// Stop waits for this last read at most streamStopTimeout, logs read afterwards are dropped.
+func (c LogCacheClient) StreamLogs(appGUID string, write func(logs string)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	stopped := false
+	emit := func(logs string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			write(logs)
+		}
+	}
+
+	startTime := time.Now()
+	read := func() {
+		for {
+			envelopes, err := c.read(ctx, appGUID, ReadOptions{
+				EnvelopeTypes: []string{EnvelopeTypeLog},
+				StartTime:     startTime,
+				Limit:         MaxLimit,
+			})
+			if err != nil {
+				log.Printf("[WARN] error when streaming logs of app %s from log cache: %s", appGUID, err.Error())
+				return
+			}
+			for _, envelope := range envelopes {
+				emit(FormatEnvelope(envelope))
+				startTime = envelope.Time().Add(time.Nanosecond)
+			}
+			if len(envelopes) < MaxLimit {
+				return
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(StreamInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				read()
+				return
+			case <-ticker.C:
+				read()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			select {
+			case <-finished:
+			case <-time.After(streamStopTimeout):
+				log.Printf("[WARN] last read of logs of app %s from log cache takes too long, logs may be missing", appGUID)
+			}
+			cancel()
+			mu.Lock()
+			stopped = true
+			mu.Unlock()
+		})
+	}, nil
+}
+
+// FormatEnvelope formats a log envelope, envelopes of other types give an empty string
+func FormatEnvelope(envelope Envelope) string {
+	if envelope.Log == nil {
+		return ""
+	}
+	return common.FormatAppLogMessage(
+		envelope.Time(),
+		envelope.Tags["source_type"],
+		envelope.InstanceID,
+		envelope.Log.Type == "ERR",
+		string(envelope.Log.Payload),
+	)
+}
@@ -0,0 +1,217 @@
+package logcache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testTokenStore string
+
+func (s testTokenStore) AccessToken() string {
+	return string(s)
+}
+
+// testLogCache is a log cache stand-in answering reads with the given envelopes json batch
+type testLogCache struct {
+	*httptest.Server
+	mu      sync.Mutex
+	batch   string
+	queries []url.Values
+}
+
+func newTestLogCache(t *testing.T, batch string) *testLogCache {
+	lc := &testLogCache{batch: batch}
+	lc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/read/app-guid" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		lc.mu.Lock()
+		lc.queries = append(lc.queries, r.URL.Query())
+		lc.mu.Unlock()
+		fmt.Fprintf(w, `{"envelopes":{"batch":[%s]}}`, lc.batch)
+	}))
+	t.Cleanup(lc.Close)
+	return lc
+}
+
+func (lc *testLogCache) client(maxMessages int) *LogCacheClient {
+	return NewLogCacheClient(lc.URL+"/", false, time.Second, testTokenStore("bearer token"), maxMessages)
+}
+
+// testEnvelope gives a log envelope as log cache json api does: timestamp as a string, payload in base64
+func testEnvelope(timestamp int64, payload string) string {
+	return fmt.Sprintf(`{"timestamp":"%d","source_id":"app-guid","instance_id":"0","tags":{"source_type":"APP/PROC/WEB"},"log":{"payload":"%s","type":"OUT"}}`,
+		timestamp, payload)
+}
+
+func TestRead(t *testing.T) {
+	lc := newTestLogCache(t, testEnvelope(1700000000123456789, "aGVsbG8gd29ybGQ="))
+	start := time.Unix(0, 1700000000000000000)
+
+	envelopes, err := lc.client(100).Read("app-guid", ReadOptions{
+		EnvelopeTypes: []string{EnvelopeTypeLog, EnvelopeTypeEvent},
+		StartTime:     start,
+		Limit:         5,
+		Descending:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := lc.queries[0]
+	if got := strings.Join(query["envelope_types"], ","); got != "LOG,EVENT" {
+		t.Errorf("expected envelope_types LOG,EVENT, got %s", got)
+	}
+	if query.Get("start_time") != "1700000000000000000" || query.Get("limit") != "5" || query.Get("descending") != "true" {
+		t.Errorf("unexpected query %v", query)
+	}
+	if _, ok := query["end_time"]; ok {
+		t.Errorf("zero end time must not be sent, got %v", query)
+	}
+
+	if len(envelopes) != 1 {
+		t.Fatalf("expected 1 envelope, got %d", len(envelopes))
+	}
+	envelope := envelopes[0]
+	if !envelope.Time().Equal(time.Unix(0, 1700000000123456789)) {
+		t.Errorf("unexpected envelope time %s", envelope.Time())
+	}
+	if envelope.Log == nil || string(envelope.Log.Payload) != "hello world" {
+		t.Errorf("unexpected envelope log %+v", envelope.Log)
+	}
+	if envelope.Tags["source_type"] != "APP/PROC/WEB" || envelope.InstanceID != "0" {
+		t.Errorf("unexpected envelope %+v", envelope)
+	}
+}
+
+func TestReadError(t *testing.T) {
+	lc := newTestLogCache(t, "")
+	client := NewLogCacheClient(lc.URL, false, time.Second, testTokenStore("bearer expired"), 100)
+
+	_, err := client.Read("app-guid", ReadOptions{})
+	if err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
+
+func TestRecentLogs(t *testing.T) {
+	// log cache gives envelopes from the most recent one when reading in descending order
+	lc := newTestLogCache(t, strings.Join([]string{
+		testEnvelope(3000000000, "dGhpcmQ="),
+		testEnvelope(2000000000, "c2Vjb25k"),
+		testEnvelope(1000000000, "Zmlyc3Q="),
+	}, ","))
+
+	logs, err := lc.client(3).RecentLogs("app-guid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, third := strings.Index(logs, "first"), strings.Index(logs, "second"), strings.Index(logs, "third")
+	if first < 0 || !(first < second && second < third) {
+		t.Errorf("expected logs from the oldest one, got:\n%s", logs)
+	}
+	if !strings.Contains(logs, "[APP/PROC/WEB/0] OUT first") {
+		t.Errorf("unexpected log format:\n%s", logs)
+	}
+	query := lc.queries[0]
+	if query.Get("envelope_types") != EnvelopeTypeLog || query.Get("limit") != "3" || query.Get("descending") != "true" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestRecentLogsLimit(t *testing.T) {
+	cases := []struct {
+		appLogsMax int
+		limit      string
+	}{
+		{appLogsMax: 30, limit: "30"},
+		{appLogsMax: 5000, limit: "1000"},
+		{appLogsMax: -1, limit: "1000"},
+		{appLogsMax: 0},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprint(c.appLogsMax), func(t *testing.T) {
+			lc := newTestLogCache(t, testEnvelope(1000000000, "Zmlyc3Q="))
+
+			logs, err := lc.client(c.appLogsMax).RecentLogs("app-guid")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.limit == "" {
+				if len(lc.queries) != 0 || logs != "" {
+					t.Errorf("expected no read when app_logs_max is 0, got %d reads", len(lc.queries))
+				}
+				return
+			}
+			if got := lc.queries[0].Get("limit"); got != c.limit {
+				t.Errorf("expected limit %s, got %s", c.limit, got)
+			}
+		})
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	lc := newTestLogCache(t, testEnvelope(time.Now().Add(time.Second).UnixNano(), "c3RhZ2luZw=="))
+	var mu sync.Mutex
+	logs := ""
+
+	stop, err := lc.client(100).StreamLogs("app-guid", func(l string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs += l
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// logs emitted since last poll are read when stopping
+	stop()
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(logs, "OUT staging") {
+		t.Errorf("expected streamed logs, got:\n%s", logs)
+	}
+	query := lc.queries[0]
+	if query.Get("envelope_types") != EnvelopeTypeLog || query.Get("start_time") == "" || query.Get("limit") != "1000" {
+		t.Errorf("unexpected query %v", query)
+	}
+}
+
+func TestStreamLogsStopDoesNotHang(t *testing.T) {
+	previousTimeout := streamStopTimeout
+	streamStopTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { streamStopTimeout = previousTimeout })
+
+	// a stalled log cache only answers when the request is canceled
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	client := NewLogCacheClient(server.URL, false, time.Second, testTokenStore("bearer token"), 100)
+
+	written := false
+	stop, err := client.StreamLogs("app-guid", func(string) { written = true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	stop()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("stop waited %s for a stalled read", elapsed)
+	}
+	if written {
+		t.Errorf("no logs expected from a stalled log cache")
+	}
+}
@@ -2,34 +2,39 @@ package noaa
 
 import (
 	"crypto/tls"
-	"fmt"
-	noaaconsumer "github.com/cloudfoundry/noaa/consumer"
-	"github.com/cloudfoundry/sonde-go/events"
+	"log"
 	"net/http"
-	"strings"
 	"time"
-)
 
-const LogTimestampFormat = "2006-01-02T15:04:05.00-0700"
+	noaaconsumer "github.com/cloudfoundry/noaa/consumer"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
+)
 
 type NOAATokenStore interface {
 	AccessToken() string
 }
 
+// NOAAClient reads logs through the traffic controller, it is used on foundations not exposing log cache
 type NOAAClient struct {
-	consumer    *noaaconsumer.Consumer
-	store       NOAATokenStore
-	maxMessages int
+	consumer             *noaaconsumer.Consumer
+	trafficControllerUrl string
+	tlsConfig            *tls.Config
+	store                NOAATokenStore
+	maxMessages          int
 }
 
 func NewNOAAClient(trafficControllerUrl string, skipSslValidation bool, store NOAATokenStore, maxMessages int) *NOAAClient {
-	consumer := noaaconsumer.New(trafficControllerUrl, &tls.Config{
+	tlsConfig := &tls.Config{
 		InsecureSkipVerify: skipSslValidation,
-	}, http.ProxyFromEnvironment)
+	}
+	consumer := noaaconsumer.New(trafficControllerUrl, tlsConfig, http.ProxyFromEnvironment)
 	return &NOAAClient{
-		consumer:    consumer,
-		store:       store,
-		maxMessages: maxMessages,
+		consumer:             consumer,
+		trafficControllerUrl: trafficControllerUrl,
+		tlsConfig:            tlsConfig,
+		store:                store,
+		maxMessages:          maxMessages,
 	}
 }
 
@@ -47,22 +52,47 @@ func (c NOAAClient) RecentLogs(appGUID string) (string, error) {
 	}
 	logs := ""
 	for i := maxLen - 1; i >= 0; i-- {
-		logMsg := logMsgs[i]
-		t := time.Unix(0, logMsg.GetTimestamp()).In(time.Local).Format(LogTimestampFormat)
-		typeMessage := "OUT"
-		if logMsg.GetMessageType() != events.LogMessage_OUT {
-			typeMessage = "ERR"
-		}
-		header := fmt.Sprintf("%s [%s/%s] %s ",
-			t,
-			logMsg.GetSourceType(),
-			logMsg.GetSourceInstance(),
-			typeMessage,
-		)
-		message := string(logMsg.GetMessage())
-		for _, line := range strings.Split(message, "\n") {
-			logs += fmt.Sprintf("\t%s%s\n", header, strings.TrimRight(line, "\r\n"))
-		}
+		logs += formatLogMessage(logMsgs[i])
 	}
 	return logs, nil
 }
+
+// StreamLogs tails logs of an app from the traffic controller until stop is called,
+// a consumer is created for each stream as closing a consumer closes all of its connections
+func (c NOAAClient) StreamLogs(appGUID string, write func(logs string)) (func(), error) {
+	consumer := noaaconsumer.New(c.trafficControllerUrl, c.tlsConfig, http.ProxyFromEnvironment)
+	logMsgs, errs := consumer.TailingLogs(appGUID, c.store.AccessToken())
+	go func() {
+		for logMsgs != nil || errs != nil {
+			select {
+			case logMsg, ok := <-logMsgs:
+				if !ok {
+					logMsgs = nil
+					continue
+				}
+				write(formatLogMessage(logMsg))
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				if err != nil {
+					log.Printf("[WARN] error when streaming logs of app %s from traffic controller: %s", appGUID, err.Error())
+				}
+			}
+		}
+	}()
+	return func() {
+		consumer.Close()
+	}, nil
+}
+
+func formatLogMessage(logMsg *events.LogMessage) string {
+	return common.FormatAppLogMessage(
+		time.Unix(0, logMsg.GetTimestamp()),
+		logMsg.GetSourceType(),
+		logMsg.GetSourceInstance(),
+		logMsg.GetMessageType() != events.LogMessage_OUT,
+		string(logMsg.GetMessage()),
+	)
+}
@@ -3,8 +3,10 @@ package raw
 import (
 	"bytes"
 	"code.cloudfoundry.org/cli/api/cloudcontroller"
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
@@ -88,3 +90,57 @@ func (c RawClient) NewRequest(method string, path string, data []byte) (*cloudco
 	cfReq := cloudcontroller.NewRequest(baseReq, reader)
 	return cfReq, nil
 }
+
+// DoJSON - Do a request with a json body (if not nil) and decode the json response into result (if not nil),
+// http status >= 400 are returned as ccerror.RawHTTPStatusError
+func (c RawClient) DoJSON(method string, path string, body interface{}, result interface{}) (http.Header, error) {
+	return c.DoJSONWithHeader(method, path, nil, body, result)
+}
+
+// DoJSONWithHeader - Same as DoJSON with additional request headers (e.g. If-Match)
+func (c RawClient) DoJSONWithHeader(method string, path string, header http.Header, body interface{}, result interface{}) (http.Header, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := c.NewRequest(method, path, data)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return resp.Header, ccerror.RawHTTPStatusError{
+			StatusCode:  resp.StatusCode,
+			RawResponse: b,
+		}
+	}
+	if result != nil && len(b) > 0 {
+		err = json.Unmarshal(b, result)
+		if err != nil {
+			return resp.Header, err
+		}
+	}
+	return resp.Header, nil
+}
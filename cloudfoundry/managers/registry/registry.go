@@ -0,0 +1,271 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultTag        = "latest"
+)
+
+// manifestMediaTypes are accepted when resolving a digest, multi-arch images are resolved to their index digest
+// like docker does when pulling
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+var challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Image is a docker image reference as given to cloud foundry
+type Image struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImage splits a docker image reference (e.g. redis:4.0 or registry.example.com:5000/user/repository@sha256:...)
+// in registry, repository, tag and digest, docker hub and latest tag are used when not given
+func ParseImage(image string) (Image, error) {
+	img := Image{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, img.Digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, img.Tag = name[:i], name[i+1:]
+	}
+	if name == "" {
+		return Image{}, fmt.Errorf("invalid docker image %s", image)
+	}
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		img.Registry, img.Repository = parts[0], parts[1]
+	} else {
+		img.Registry, img.Repository = dockerHubRegistry, name
+	}
+	if img.Registry == "docker.io" || img.Registry == "index.docker.io" {
+		img.Registry = dockerHubRegistry
+	}
+	if img.Registry == dockerHubRegistry && !strings.Contains(img.Repository, "/") {
+		img.Repository = "library/" + img.Repository
+	}
+	if img.Tag == "" && img.Digest == "" {
+		img.Tag = defaultTag
+	}
+	return img, nil
+}
+
+// Name gives an image reference without tag nor digest
+func Name(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// Pin gives the image reference pinned to a digest
+func Pin(image string, digest string) string {
+	return Name(image) + "@" + digest
+}
+
+// Client resolves docker image tags to digests with the docker registry http api v2
+type Client struct {
+	httpClient *http.Client
+	// digests are the digests already resolved by image and username, a tag is resolved once
+	// by provider run even when used by many apps
+	digests map[string]string
+	mu      *sync.Mutex
+}
+
+// NewClient -
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+		digests:    make(map[string]string),
+		mu:         &sync.Mutex{},
+	}
+}
+
+// ResolveDigest gives the digest of the manifest an image tag points to, credentials are optional.
+// Images already pinned to a digest are not resolved.
+func (c Client) ResolveDigest(image string, username string, password string) (string, error) {
+	key := image + "\n" + username
+	c.mu.Lock()
+	digest, ok := c.digests[key]
+	c.mu.Unlock()
+	if ok {
+		return digest, nil
+	}
+	digest, err := c.resolveDigest(image, username, password)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.digests[key] = digest
+	c.mu.Unlock()
+	return digest, nil
+}
+
+func (c Client) resolveDigest(image string, username string, password string) (string, error) {
+	img, err := ParseImage(image)
+	if err != nil {
+		return "", err
+	}
+	if img.Digest != "" {
+		return img.Digest, nil
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registryScheme(img.Registry), img.Registry, img.Repository, img.Tag)
+
+	resp, err := c.manifestRequest(http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	authorization := ""
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err = c.authorize(resp.Header.Get("WWW-Authenticate"), username, password)
+		if err != nil {
+			return "", err
+		}
+		resp, err = c.manifestRequest(http.MethodHead, manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+	}
+	if err := checkResponse(resp, image); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// registry does not give digest on head request, it is computed from the manifest
+	resp, err = c.manifestRequest(http.MethodGet, manifestURL, authorization)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, image); err != nil {
+		return "", err
+	}
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (c Client) manifestRequest(method string, manifestURL string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient.Do(req)
+}
+
+// authorize answers the authentication challenge of the registry with basic auth
+// or with a bearer token retrieved from the registry token service
+func (c Client) authorize(challenge string, username string, password string) (string, error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	if scheme == "basic" {
+		if username == "" {
+			return "", fmt.Errorf("docker registry requires credentials")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	}
+	if scheme != "bearer" {
+		return "", fmt.Errorf("unsupported docker registry authentication challenge '%s'", challenge)
+	}
+
+	params := make(map[string]string)
+	for _, m := range challengeParamRegex.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid docker registry authentication realm in challenge '%s'", challenge)
+	}
+	query := tokenURL.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v, ok := params[k]; ok {
+			query.Set(k, v)
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("docker registry token service %s responded %s", tokenURL.Host, strings.TrimSpace(resp.Status))
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func checkResponse(resp *http.Response, image string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf("docker registry responded %s for image %s", strings.TrimSpace(resp.Status), image)
+}
+
+// registryScheme gives http for registries on loopback addresses, as docker allows them without tls by default
+func registryScheme(registry string) string {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return "http"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+	return "https"
+}
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testManifest = `{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.v2+json"}`
+
+// testRegistry is a registry stand-in serving one manifest for repository user/app, tag latest
+type testRegistry struct {
+	*httptest.Server
+	auth         string
+	headDigest   bool
+	manifestReqs []string
+}
+
+func newTestRegistry(t *testing.T, auth string, headDigest bool) *testRegistry {
+	reg := &testRegistry{auth: auth, headDigest: headDigest}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "repository:user/app:pull" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"token": "registry-token"}`)
+	})
+	mux.HandleFunc("/v2/user/app/manifests/latest", func(w http.ResponseWriter, r *http.Request) {
+		reg.manifestReqs = append(reg.manifestReqs, r.Method)
+		switch reg.auth {
+		case "bearer":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:user/app:pull"`, reg.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "basic":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.docker.distribution.manifest.v2+json") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// without digest header, digest must be computed from the manifest
+		if reg.headDigest {
+			w.Header().Set("Docker-Content-Digest", "sha256:"+testManifestSHA256())
+		}
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, testManifest)
+		}
+	})
+	reg.Server = httptest.NewServer(mux)
+	t.Cleanup(reg.Close)
+	return reg
+}
+
+func (r *testRegistry) image(path string) string {
+	return strings.TrimPrefix(r.URL, "http://") + path
+}
+
+func testManifestSHA256() string {
+	sum := sha256.Sum256([]byte(testManifest))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseImage(t *testing.T) {
+	cases := []struct {
+		image    string
+		expected Image
+	}{
+		{"redis", Image{Registry: "registry-1.docker.io", Repository: "library/redis", Tag: "latest"}},
+		{"redis:4.0", Image{Registry: "registry-1.docker.io", Repository: "library/redis", Tag: "4.0"}},
+		{"docker.io/user/app:1", Image{Registry: "registry-1.docker.io", Repository: "user/app", Tag: "1"}},
+		{"registry.example.com:5000/user/repository/tag", Image{Registry: "registry.example.com:5000", Repository: "user/repository/tag", Tag: "latest"}},
+		{"localhost/app@sha256:abc", Image{Registry: "localhost", Repository: "app", Digest: "sha256:abc"}},
+		{"localhost:5000/app:1@sha256:abc", Image{Registry: "localhost:5000", Repository: "app", Tag: "1", Digest: "sha256:abc"}},
+	}
+	for _, c := range cases {
+		img, err := ParseImage(c.image)
+		if err != nil {
+			t.Fatalf("%s: %s", c.image, err)
+		}
+		if img != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.image, c.expected, img)
+		}
+	}
+}
+
+func TestPin(t *testing.T) {
+	cases := map[string]string{
+		"redis":                            "redis@sha256:abc",
+		"redis:4.0":                        "redis@sha256:abc",
+		"localhost:5000/app:1":             "localhost:5000/app@sha256:abc",
+		"localhost:5000/app@sha256:old":    "localhost:5000/app@sha256:abc",
+		"registry.example.com:5000/u/repo": "registry.example.com:5000/u/repo@sha256:abc",
+	}
+	for image, expected := range cases {
+		if pinned := Pin(image, "sha256:abc"); pinned != expected {
+			t.Errorf("%s: expected %s, got %s", image, expected, pinned)
+		}
+	}
+}
+
+func TestResolveDigest(t *testing.T) {
+	expected := "sha256:" + testManifestSHA256()
+
+	cases := []struct {
+		name       string
+		auth       string
+		headDigest bool
+		username   string
+		reqs       string
+		err        string
+	}{
+		{name: "anonymous", headDigest: true, reqs: "HEAD"},
+		{name: "digest computed from manifest", reqs: "HEAD,GET"},
+		{name: "bearer token", auth: "bearer", headDigest: true, username: "user", reqs: "HEAD,HEAD"},
+		{name: "basic auth", auth: "basic", headDigest: true, username: "user", reqs: "HEAD,HEAD"},
+		{name: "missing credentials", auth: "basic", reqs: "HEAD", err: "docker registry requires credentials"},
+		{name: "wrong credentials", auth: "bearer", username: "other", reqs: "HEAD", err: "responded 401 Unauthorized"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reg := newTestRegistry(t, c.auth, c.headDigest)
+			client := NewClient(reg.Client())
+
+			digest, err := client.ResolveDigest(reg.image("/user/app"), c.username, "secret")
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q, got %v", c.err, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if digest != expected {
+				t.Errorf("expected digest %s, got %s", expected, digest)
+			}
+			if reqs := strings.Join(reg.manifestReqs, ","); reqs != c.reqs {
+				t.Errorf("expected manifest requests %s, got %s", c.reqs, reqs)
+			}
+		})
+	}
+}
+
+func TestResolveDigestErrors(t *testing.T) {
+	reg := newTestRegistry(t, "", true)
+	client := NewClient(reg.Client())
+
+	_, err := client.ResolveDigest(reg.image("/user/unknown:1.0"), "", "")
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	// pinned images are not resolved
+	digest, err := client.ResolveDigest(reg.image("/user/unknown@sha256:abc"), "", "")
+	if err != nil || digest != "sha256:abc" {
+		t.Errorf("expected pinned digest, got %s, %v", digest, err)
+	}
+	if len(reg.manifestReqs) != 0 {
+		t.Errorf("expected no manifest request, got %v", reg.manifestReqs)
+	}
+}
+
+func TestResolveDigestCache(t *testing.T) {
+	reg := newTestRegistry(t, "", true)
+	client := NewClient(reg.Client())
+
+	for i := 0; i < 3; i++ {
+		digest, err := client.ResolveDigest(reg.image("/user/app"), "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := "sha256:" + testManifestSHA256(); digest != expected {
+			t.Errorf("expected digest %s, got %s", expected, digest)
+		}
+	}
+	if reqs := strings.Join(reg.manifestReqs, ","); reqs != "HEAD" {
+		t.Errorf("expected tag to be resolved once, got manifest requests %s", reqs)
+	}
+
+	// failures are not kept, registry is asked again
+	if _, err := client.ResolveDigest(reg.image("/user/unknown:1.0"), "", ""); err == nil {
+		t.Fatal("expected not found error")
+	}
+	if len(client.digests) != 1 {
+		t.Errorf("expected only resolved digests to be kept, got %v", client.digests)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"os"
@@ -22,10 +23,13 @@ import (
 	uaaWrapper "code.cloudfoundry.org/cli/api/uaa/wrapper"
 	"code.cloudfoundry.org/cli/command/translatableerror"
 	"code.cloudfoundry.org/cli/util/configv3"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/appdeployers"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/logcache"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/noaa"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/registry"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/v3appdeployers"
 
 	goClient "github.com/cloudfoundry/go-cfclient/v3/client"
@@ -48,11 +52,20 @@ type Session struct {
 	// To call tcp routing with this router
 	RouterClient *router.Client
 
+	// Used for direct routing api calls not covered by RouterClient
+	RoutingRawClient *raw.RawClient
+
+	// Used for direct uaa api calls not covered by ClientUAA, authenticated like ClientUAA
+	UAARawClient *raw.RawClient
+
 	// Manage upload bits like app and buildpack in full stream
 	BitsManager *bits.BitsManager
 
-	// NOAAClient permit to access to apps logs
-	NOAAClient *noaa.NOAAClient
+	// RegistryClient permit to resolve docker images tags to digests
+	RegistryClient *registry.Client
+
+	// LogsClient permit to access to apps logs through log cache, or NOAA when log cache is not available
+	LogsClient common.AppLogsClient
 
 	// NetClient permit to access to networking policy api
 	NetClient *cfnetv1.Client
@@ -141,6 +154,7 @@ func NewSession(c Config) (s *Session, err error) {
 		return nil, fmt.Errorf("Error when creating clients: %s", err.Error())
 	}
 	s.BitsManager = bits.NewBitsManager(s.ClientV2, s.ClientV3, s.RawClient, s.HttpClient)
+	s.RegistryClient = registry.NewClient(s.HttpClient)
 
 	err = s.loadDefaultQuotaGuid(c.DefaultQuotaName)
 	if err != nil {
@@ -326,6 +340,21 @@ func (s *Session) init(config *configv3.Config, configUaa *configv3.Config, conf
 		configUaa.SetRefreshToken(refreshTokenSess)
 		s.ClientUAA = uaaClientSess
 		uaaAuthWrapperSess.SetClient(uaaClientSess)
+
+		authWrapperUAARaw := ccWrapper.NewUAAAuthentication(nil, configUaa)
+		authWrapperUAARaw.SetClient(uaaClientSess)
+		uaaRawWrappers := []ccv3.ConnectionWrapper{
+			authWrapperUAARaw,
+			NewRetryRequest(config.RequestRetryCount()),
+		}
+		if IsDebugMode() {
+			uaaRawWrappers = append(uaaRawWrappers, ccWrapper.NewRequestLogger(NewRequestLogger()))
+		}
+		s.UAARawClient = raw.NewRawClient(raw.RawClientConfig{
+			ApiEndpoint:       uaaClientSess.UAALink(),
+			SkipSSLValidation: config.SkipSSLValidation(),
+			DialTimeout:       config.DialTimeout(),
+		}, uaaRawWrappers...)
 	}
 	// -------------------------
 
@@ -403,25 +432,58 @@ func (s *Session) init(config *configv3.Config, configUaa *configv3.Config, conf
 	routerConfig.Wrappers = routerWrappers
 
 	s.RouterClient = router.NewClient(routerConfig)
+
+	s.RoutingRawClient = raw.NewRawClient(raw.RawClientConfig{
+		ApiEndpoint:       ccClientV2.RoutingEndpoint(),
+		SkipSSLValidation: config.SkipSSLValidation(),
+		DialTimeout:       config.DialTimeout(),
+	}, rawWrappers...)
 	// -------------------------
 
 	// -------------------------
-	// Create NOAA client for accessing logs from an app
-	s.NOAAClient = noaa.NewNOAAClient(s.ClientV3.Logging(), config.SkipSSLValidation(), config, configSess.AppLogsMax)
+	// Create log cache client for accessing logs from an app, fallback on NOAA for foundations without log cache
+	s.loadLogsClient(config, configSess.AppLogsMax)
 	// -------------------------
 
 	return nil
 }
 
+// loadLogsClient sets LogsClient on log cache, or on NOAA when log cache is not deployed or can't be found
+func (s *Session) loadLogsClient(config *configv3.Config, appLogsMax int) {
+	logCacheURL, err := s.logCacheEndpoint()
+	if err != nil {
+		log.Printf("[WARN] Unable to find log cache endpoint, falling back on NOAA for app logs: %s", err)
+	}
+	if logCacheURL != "" {
+		s.LogsClient = logcache.NewLogCacheClient(logCacheURL, config.SkipSSLValidation(), config.DialTimeout(), config, appLogsMax)
+	} else {
+		s.LogsClient = noaa.NewNOAAClient(s.ClientV3.Logging(), config.SkipSSLValidation(), config, appLogsMax)
+	}
+}
+
+// logCacheEndpoint returns log cache url given in root links of cloud controller, empty when log cache is not deployed
+func (s *Session) logCacheEndpoint() (string, error) {
+	var root struct {
+		Links map[string]struct {
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	_, err := s.RawClient.DoJSON(http.MethodGet, "/", nil, &root)
+	if err != nil {
+		return "", fmt.Errorf("Error when retrieving root links: %s", err)
+	}
+	return root.Links["log_cache"].Href, nil
+}
+
 func (s *Session) loadDeployer() {
-	s.RunBinder = appdeployers.NewRunBinder(s.ClientV2, s.NOAAClient)
+	s.RunBinder = appdeployers.NewRunBinder(s.ClientV2, s.LogsClient, s.Config.AppLogsDir)
 	stdStrategy := appdeployers.NewStandard(s.BitsManager, s.ClientV2, s.RunBinder)
 	bgStrategy := appdeployers.NewBlueGreenV2(s.BitsManager, s.ClientV2, s.ClientV3, s.RawClient, s.RunBinder, stdStrategy)
 	s.Deployer = appdeployers.NewDeployer(stdStrategy, bgStrategy)
 
 	// Initialize deployment strategies in v3
-	s.V3RunBinder = v3appdeployers.NewRunBinder(s.ClientV3, s.ClientGo, s.NOAAClient)
-	v3std := v3appdeployers.NewStandard(s.BitsManager, s.ClientV3, s.V3RunBinder)
+	s.V3RunBinder = v3appdeployers.NewRunBinder(s.ClientV3, s.ClientGo, s.LogsClient, s.Config.AppLogsDir)
+	v3std := v3appdeployers.NewStandard(s.BitsManager, s.ClientV3, s.RawClient, s.V3RunBinder)
 	v3bg := v3appdeployers.NewBlueGreen(s.BitsManager, s.ClientV3, s.RawClient, s.V3RunBinder, v3std)
 
 	// Initialize deployer for rolling
@@ -0,0 +1,72 @@
+package managers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/util/configv3"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/logcache"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/noaa"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
+)
+
+func TestLoadLogsClient(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		root     string
+		logCache bool
+	}{
+		{
+			name:     "log cache in root links",
+			status:   http.StatusOK,
+			root:     `{"links":{"self":{"href":"https://api.example.com"},"log_cache":{"href":"https://log-cache.example.com"}}}`,
+			logCache: true,
+		},
+		{
+			name:   "no log cache in root links",
+			status: http.StatusOK,
+			root:   `{"links":{"self":{"href":"https://api.example.com"},"logging":{"href":"wss://doppler.example.com:443"}}}`,
+		},
+		{
+			name:   "root links not readable",
+			status: http.StatusInternalServerError,
+			root:   `{"errors":[]}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(c.status)
+				fmt.Fprint(w, c.root)
+			}))
+			defer server.Close()
+
+			s := &Session{
+				ClientV3:  ccv3.NewClient(ccv3.Config{AppName: "test", AppVersion: "test"}),
+				RawClient: raw.NewRawClient(raw.RawClientConfig{ApiEndpoint: server.URL}),
+			}
+			s.loadLogsClient(&configv3.Config{}, 100)
+
+			switch s.LogsClient.(type) {
+			case *logcache.LogCacheClient:
+				if !c.logCache {
+					t.Errorf("expected NOAA client, got log cache client")
+				}
+			case *noaa.NOAAClient:
+				if c.logCache {
+					t.Errorf("expected log cache client, got NOAA client")
+				}
+			default:
+				t.Errorf("unexpected logs client %T", s.LogsClient)
+			}
+		})
+	}
+}
@@ -29,17 +29,23 @@ func (a Actor) CreateApplication(appDeploy AppDeploy, reverse FallbackFunction)
 				deployFunc = a.client.CreateApplication
 			}
 
-			if appDeploy.IsDockerImage() {
-				app.LifecycleType = constant.AppLifecycleTypeDocker
-			}
+			var application resources.Application
+			var err error
+			if appDeploy.IsCNB() {
+				application, err = CreateOrUpdateCNBApplication(a.rawClient, app, appDeploy.CNBCredentials)
+			} else {
+				if appDeploy.IsDockerImage() {
+					app.LifecycleType = constant.AppLifecycleTypeDocker
+				}
 
-			// Only set lifecycle type for explicit buildpack declaration
-			// This is to avoid cloudcontroller error on empty buildpack name
-			if bpkg := appDeploy.App.LifecycleBuildpacks; len(bpkg) > 0 && bpkg[0] != "" {
-				app.LifecycleType = constant.AppLifecycleTypeBuildpack
-			}
+				// Only set lifecycle type for explicit buildpack declaration
+				// This is to avoid cloudcontroller error on empty buildpack name
+				if bpkg := appDeploy.App.LifecycleBuildpacks; len(bpkg) > 0 && bpkg[0] != "" {
+					app.LifecycleType = constant.AppLifecycleTypeBuildpack
+				}
 
-			application, _, err := deployFunc(app)
+				application, _, err = deployFunc(app)
+			}
 			if err != nil {
 				return ctx, err
 			}
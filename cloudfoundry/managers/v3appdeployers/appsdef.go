@@ -5,6 +5,7 @@ import (
 
 	"code.cloudfoundry.org/cli/resources"
 	"code.cloudfoundry.org/cli/types"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
 )
 
 type AppDeploy struct {
@@ -16,10 +17,12 @@ type AppDeploy struct {
 	ServiceBindings []resources.ServiceCredentialBinding
 	EnvVars         map[string]interface{}
 	Path            string
+	PathDownload    bits.DownloadOptions
 	BindTimeout     time.Duration
 	StageTimeout    time.Duration
 	StartTimeout    time.Duration
 	Ports           []int
+	CNBCredentials  CNBCredentials
 }
 
 func (a AppDeploy) IsDockerImage() bool {
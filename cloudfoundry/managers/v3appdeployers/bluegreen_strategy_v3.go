@@ -80,9 +80,11 @@ func (s BlueGreen) Deploy(appDeploy AppDeploy) (AppDeployResponse, error) {
 					ServiceBindings: appDeploy.ServiceBindings,
 					Mappings:        appDeploy.Mappings,
 					Path:            appDeploy.Path,
+					PathDownload:    appDeploy.PathDownload,
 					StageTimeout:    appDeploy.StageTimeout,
 					BindTimeout:     appDeploy.BindTimeout,
 					StartTimeout:    appDeploy.StartTimeout,
+					CNBCredentials:  appDeploy.CNBCredentials,
 				})
 				ctx["app_response"] = appResp
 				return ctx, err
@@ -224,6 +226,7 @@ func (s BlueGreen) Restage(appDeploy AppDeploy) (AppDeployResponse, error) {
 					StageTimeout:    appDeploy.StageTimeout,
 					BindTimeout:     appDeploy.BindTimeout,
 					StartTimeout:    appDeploy.StartTimeout,
+					CNBCredentials:  appDeploy.CNBCredentials,
 				})
 				ctx["app_response"] = appResp
 				return ctx, err
@@ -0,0 +1,99 @@
+package v3appdeployers
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	"code.cloudfoundry.org/cli/resources"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
+)
+
+// AppLifecycleTypeCNB is the cloud native buildpacks lifecycle, it is not handled by cli client
+// so apps and builds with this lifecycle are sent through raw client
+const AppLifecycleTypeCNB constant.AppLifecycleType = "cnb"
+
+// CNBCredential is a registry credential used by cnb lifecycle to fetch buildpacks
+type CNBCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CNBCredentials are registry credentials indexed by registry host
+type CNBCredentials map[string]CNBCredential
+
+type cnbLifecycleData struct {
+	Buildpacks  []string       `json:"buildpacks,omitempty"`
+	Stack       string         `json:"stack,omitempty"`
+	Credentials CNBCredentials `json:"credentials,omitempty"`
+}
+
+type cnbLifecycle struct {
+	Type constant.AppLifecycleType `json:"type"`
+	Data cnbLifecycleData          `json:"data"`
+}
+
+type cnbApplication struct {
+	Name          string                  `json:"name,omitempty"`
+	Relationships resources.Relationships `json:"relationships,omitempty"`
+	Lifecycle     cnbLifecycle            `json:"lifecycle"`
+	Metadata      *resources.Metadata     `json:"metadata,omitempty"`
+}
+
+type cnbBuild struct {
+	Package struct {
+		GUID string `json:"guid"`
+	} `json:"package"`
+	Lifecycle cnbLifecycle `json:"lifecycle"`
+}
+
+// IsCNB returns true when app must be staged with cloud native buildpacks
+func (a AppDeploy) IsCNB() bool {
+	return a.App.LifecycleType == AppLifecycleTypeCNB
+}
+
+func newCNBLifecycle(app resources.Application, credentials CNBCredentials) cnbLifecycle {
+	return cnbLifecycle{
+		Type: AppLifecycleTypeCNB,
+		Data: cnbLifecycleData{
+			Buildpacks:  app.LifecycleBuildpacks,
+			Stack:       app.StackName,
+			Credentials: credentials,
+		},
+	}
+}
+
+// CreateOrUpdateCNBApplication creates an app with cnb lifecycle, or updates it if app guid is set
+func CreateOrUpdateCNBApplication(rawClient *raw.RawClient, app resources.Application, credentials CNBCredentials) (resources.Application, error) {
+	body := cnbApplication{
+		Name:      app.Name,
+		Lifecycle: newCNBLifecycle(app, credentials),
+		Metadata:  app.Metadata,
+	}
+	method := http.MethodPost
+	path := "/v3/apps"
+	if app.GUID != "" {
+		method = http.MethodPatch
+		path = fmt.Sprintf("/v3/apps/%s", app.GUID)
+	} else {
+		body.Relationships = resources.Relationships{
+			constant.RelationshipTypeSpace: resources.Relationship{GUID: app.SpaceGUID},
+		}
+	}
+
+	var application resources.Application
+	_, err := rawClient.DoJSON(method, path, body, &application)
+	return application, err
+}
+
+// createCNBBuild stages a package with the cnb lifecycle declared for the app,
+// it overrides the lifecycle stored in the app for this build
+func createCNBBuild(rawClient *raw.RawClient, app resources.Application, packageGUID string, credentials CNBCredentials) (resources.Build, error) {
+	var body cnbBuild
+	body.Package.GUID = packageGUID
+	body.Lifecycle = newCNBLifecycle(app, credentials)
+
+	var build resources.Build
+	_, err := rawClient.DoJSON(http.MethodPost, "/v3/builds", body, &build)
+	return build, err
+}
@@ -21,7 +21,7 @@ func (a Actor) CreateApplicationBitsPackage(appDeploy AppDeploy, reverse Fallbac
 				if appDeploy.Path == "" {
 					return ctx, nil
 				}
-				pkg, _, err = a.bitsManager.CreateAndUploadBitsPackage(appResp.App.GUID, appDeploy.Path, appDeploy.StageTimeout)
+				pkg, _, err = a.bitsManager.CreateAndUploadBitsPackage(appResp.App.GUID, appDeploy.Path, appDeploy.PathDownload, appDeploy.StageTimeout)
 			}
 			if err != nil {
 				return ctx, err
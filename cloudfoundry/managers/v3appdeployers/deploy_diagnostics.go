@@ -0,0 +1,174 @@
+package v3appdeployers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
+	goClient "github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
+)
+
+const (
+	eventTypeAppCrash = "audit.app.process.crash"
+
+	// maxCrashEvents is the number of most recent crash events reported when an app fails
+	maxCrashEvents = 5
+)
+
+// appCrash is the data of an audit.app.process.crash event
+type appCrash struct {
+	Index           int    `json:"index"`
+	ExitStatus      int    `json:"exit_status"`
+	ExitDescription string `json:"exit_description"`
+	Reason          string `json:"reason"`
+	CreatedAt       time.Time
+}
+
+// processDeployErr adds to a deployment failure recent logs, state of process instances, recent crashes
+// and buildpacks detected at staging, each one as a detail of a common.DeployError
+func (r RunBinder) processDeployErr(origErr error, appDeploy AppDeploy) error {
+	var deployErr *common.DeployError
+	if errors.As(origErr, &deployErr) {
+		return origErr
+	}
+	deployErr = &common.DeployError{
+		Err: origErr,
+	}
+	app := appDeploy.App
+
+	logs, err := r.logsClient.RecentLogs(app.GUID)
+	if err != nil {
+		logs = fmt.Sprintf("Error occurred when recolting app %s logs: %s", app.Name, err.Error())
+	}
+	deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+		Summary: fmt.Sprintf("App '%s' logs", app.Name),
+		Detail:  logs,
+	})
+
+	crashes, err := r.appCrashes(app.GUID)
+	if err != nil {
+		deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+			Summary: fmt.Sprintf("App '%s' crashes", app.Name),
+			Detail:  fmt.Sprintf("Error occurred when retrieving crash events: %s", err.Error()),
+		})
+	} else if len(crashes) > 0 {
+		lines := make([]string, 0, len(crashes))
+		for _, c := range crashes {
+			lines = append(lines, fmt.Sprintf("%s instance %d exited with status %d (%s): %s",
+				c.CreatedAt.In(time.Local).Format(common.LogTimestampFormat), c.Index, c.ExitStatus, c.Reason, c.ExitDescription))
+		}
+		deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+			Summary: fmt.Sprintf("App '%s' recent crashes", app.Name),
+			Detail:  strings.Join(lines, "\n"),
+		})
+	}
+
+	instances, err := r.instancesDiagnostic(app.GUID, crashes)
+	if err != nil {
+		instances = fmt.Sprintf("Error occurred when retrieving process instances: %s", err.Error())
+	}
+	if instances != "" {
+		deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+			Summary: fmt.Sprintf("App '%s' instances", app.Name),
+			Detail:  instances,
+		})
+	}
+
+	buildpacks, err := r.buildpacksDiagnostic(app.GUID)
+	if err != nil {
+		buildpacks = fmt.Sprintf("Error occurred when retrieving droplet: %s", err.Error())
+	}
+	if buildpacks != "" {
+		deployErr.Details = append(deployErr.Details, common.DeployErrorDetail{
+			Summary: fmt.Sprintf("App '%s' staging droplet", app.Name),
+			Detail:  buildpacks,
+		})
+	}
+	return deployErr
+}
+
+// appCrashes gives most recent crashes of the app, the most recent one first
+func (r RunBinder) appCrashes(appGUID string) ([]appCrash, error) {
+	opts := goClient.NewAuditEventListOptions()
+	opts.Types.EqualTo(eventTypeAppCrash)
+	opts.TargetGUIDs.EqualTo(appGUID)
+	opts.OrderBy = "-created_at"
+	opts.PerPage = maxCrashEvents
+	events, _, err := r.clientGo.AuditEvents.List(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	crashes := make([]appCrash, 0, len(events))
+	for _, event := range events {
+		var crash appCrash
+		if event.Data != nil {
+			err := json.Unmarshal(*event.Data, &crash)
+			if err != nil {
+				return nil, err
+			}
+		}
+		crash.CreatedAt = event.CreatedAt
+		crashes = append(crashes, crash)
+	}
+	return crashes, nil
+}
+
+// instancesDiagnostic describes instances of the web process, the exit description of the last crash
+// of an instance is given for instances which are not running
+func (r RunBinder) instancesDiagnostic(appGUID string, crashes []appCrash) (string, error) {
+	process, _, err := r.client.GetApplicationProcessByType(appGUID, constant.ProcessTypeWeb)
+	if err != nil {
+		return "", err
+	}
+	instances, _, err := r.client.GetProcessInstances(process.GUID)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		line := fmt.Sprintf("instance %d: state %s, uptime %s", instance.Index, instance.State, instance.Uptime)
+		if instance.Details != "" {
+			line += fmt.Sprintf(", details: %s", instance.Details)
+		}
+		if instance.State != constant.ProcessInstanceRunning {
+			for _, c := range crashes {
+				if int64(c.Index) == instance.Index {
+					line += fmt.Sprintf(", last exit: %s", c.ExitDescription)
+					break
+				}
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// buildpacksDiagnostic describes the most recent droplet of the app, which is the one staged
+// during the deployment even when staging failed
+func (r RunBinder) buildpacksDiagnostic(appGUID string) (string, error) {
+	opts := goClient.NewDropletAppListOptions()
+	opts.OrderBy = "-created_at"
+	opts.PerPage = 1
+	droplets, _, err := r.clientGo.Droplets.ListForApp(context.Background(), appGUID, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(droplets) == 0 {
+		return "", nil
+	}
+	droplet := droplets[0]
+	lines := []string{fmt.Sprintf("droplet %s: state %s, stack %s", droplet.GUID, droplet.State, droplet.Stack)}
+	if droplet.Error != nil && *droplet.Error != "" {
+		lines = append(lines, fmt.Sprintf("error: %s", *droplet.Error))
+	}
+	for _, bp := range droplet.Buildpacks {
+		lines = append(lines, fmt.Sprintf("buildpack %s: detected as %s version %s, detect output: %s",
+			bp.Name, bp.BuildpackName, bp.Version, bp.DetectOutput))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -1,6 +1,7 @@
 package v3appdeployers
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -9,20 +10,22 @@ import (
 	"code.cloudfoundry.org/cli/resources"
 	"code.cloudfoundry.org/cli/types"
 	goClient "github.com/cloudfoundry/go-cfclient/v3/client"
+	goResource "github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
-	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/noaa"
 )
 
 type RunBinder struct {
 	client     *ccv3.Client
-	noaaClient *noaa.NOAAClient
+	logsClient common.AppLogsClient
+	logsDir    string
 	clientGo   *goClient.Client
 }
 
-func NewRunBinder(client *ccv3.Client, clientGo *goClient.Client, noaaClient *noaa.NOAAClient) *RunBinder {
+func NewRunBinder(client *ccv3.Client, clientGo *goClient.Client, logsClient common.AppLogsClient, logsDir string) *RunBinder {
 	return &RunBinder{
 		client:     client,
-		noaaClient: noaaClient,
+		logsClient: logsClient,
+		logsDir:    logsDir,
 		clientGo:   clientGo,
 	}
 }
@@ -225,6 +228,8 @@ func (r RunBinder) BindServiceInstances(appDeploy AppDeploy) ([]resources.Servic
 
 // WaitStart checks the state of each process instance
 func (r RunBinder) WaitStart(appDeploy AppDeploy) error {
+	stopLogs := r.streamLogs(appDeploy.App, "startup")
+	defer stopLogs()
 	err := common.PollingWithTimeout(func() (bool, error) {
 		process, _, err := r.client.GetApplicationProcessByType(appDeploy.App.GUID, constant.ProcessTypeWeb)
 		if err != nil {
@@ -260,6 +265,8 @@ func (r RunBinder) WaitStart(appDeploy AppDeploy) error {
 }
 
 func (r RunBinder) WaitStaging(appDeploy AppDeploy) error {
+	stopLogs := r.streamLogs(appDeploy.App, "staging")
+	defer stopLogs()
 	err := common.PollingWithTimeout(func() (bool, error) {
 		appGUID := appDeploy.App.GUID
 		appPackages, _, err := r.client.GetPackages(ccv3.Query{
@@ -344,6 +351,7 @@ func (r RunBinder) Start(appDeploy AppDeploy) (resources.Application, resources.
 		}
 
 		// Poll once every 5 sec, timeout ${stageTimeout} fixed by appDeploy
+		stopLogs := r.streamLogs(appDeploy.App, "staging")
 		err = common.PollingWithTimeout(func() (bool, error) {
 
 			ccBuild, _, err := r.client.GetBuild(build.GUID)
@@ -361,6 +369,7 @@ func (r RunBinder) Start(appDeploy AppDeploy) (resources.Application, resources.
 
 			return false, nil
 		}, 5*time.Second, appDeploy.StageTimeout)
+		stopLogs()
 
 		if err != nil {
 			return resources.Application{}, resources.Process{}, r.processDeployErr(err, appDeploy)
@@ -464,12 +473,7 @@ func (r RunBinder) Restart(appDeploy AppDeploy) (resources.Application, resource
 	return app, proc, nil
 }
 
-func (r RunBinder) processDeployErr(origErr error, appDeploy AppDeploy) error {
-	var err error
-	var logs string
-	logs, err = r.noaaClient.RecentLogs(appDeploy.App.GUID)
-	if err != nil {
-		logs = fmt.Sprintf("Error occurred when recolting app %s logs: %s", appDeploy.App.Name, err.Error())
-	}
-	return fmt.Errorf("%s\n\nApp '%s' logs:\n%s", origErr.Error(), appDeploy.App.Name, logs)
+// streamLogs follows app logs during a deployment step, returned func stops following
+func (r RunBinder) streamLogs(app resources.Application, step string) func() {
+	return common.StreamAppLogs(r.logsClient, r.logsDir, app.GUID, app.Name, step)
 }
@@ -26,18 +26,25 @@ func (a Actor) StageApplicationPackage(appDeploy AppDeploy, reverse FallbackFunc
 			packageGUID := pkg.GUID
 
 			// Stage the package
-			build, _, err := a.client.CreateBuild(resources.Build{
-				PackageGUID: packageGUID,
-			})
+			var build resources.Build
+			if appDeploy.IsCNB() {
+				build, err = createCNBBuild(a.rawClient, appResp.App, packageGUID, appDeploy.CNBCredentials)
+			} else {
+				build, _, err = a.client.CreateBuild(resources.Build{
+					PackageGUID: packageGUID,
+				})
+			}
 			if err != nil {
 				return ctx, err
 			}
 
 			// Poll once every 5 sec, timeout ${stageTimeout} fixed by appDeploy
+			stopLogs := a.runBinder.streamLogs(appResp.App, "staging")
 			err = a.WaitStaging(build.GUID, appDeploy.StageTimeout)
+			stopLogs()
 
 			if err != nil {
-				return ctx, err
+				return ctx, a.runBinder.processDeployErr(err, AppDeploy{App: appResp.App})
 			}
 
 			// Get staged package
@@ -10,20 +10,23 @@ import (
 	"code.cloudfoundry.org/cli/resources"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/raw"
 )
 
 // Standard is the standard deployment strategy using v3 API
 type Standard struct {
 	bitsManager *bits.BitsManager
 	client      *ccv3.Client
+	rawClient   *raw.RawClient
 	runBinder   *RunBinder
 }
 
 // NewStandard initializes a v3 standard deployment strategy
-func NewStandard(bitsManager *bits.BitsManager, client *ccv3.Client, runBinder *RunBinder) *Standard {
+func NewStandard(bitsManager *bits.BitsManager, client *ccv3.Client, rawClient *raw.RawClient, runBinder *RunBinder) *Standard {
 	return &Standard{
 		bitsManager: bitsManager,
 		client:      client,
+		rawClient:   rawClient,
 		runBinder:   runBinder,
 	}
 }
@@ -61,13 +64,18 @@ func (s Standard) Deploy(appDeploy AppDeploy) (AppDeployResponse, error) {
 					app.SpaceGUID = ""
 				}
 
-				if appDeploy.IsDockerImage() {
-					app.LifecycleType = constant.AppLifecycleTypeDocker
+				var err error
+				if appDeploy.IsCNB() {
+					app, err = CreateOrUpdateCNBApplication(s.rawClient, app, appDeploy.CNBCredentials)
 				} else {
-					app.LifecycleType = constant.AppLifecycleTypeBuildpack
-				}
+					if appDeploy.IsDockerImage() {
+						app.LifecycleType = constant.AppLifecycleTypeDocker
+					} else {
+						app.LifecycleType = constant.AppLifecycleTypeBuildpack
+					}
 
-				app, _, err := deployFunc(app)
+					app, _, err = deployFunc(app)
+				}
 				if err != nil {
 					return ctx, err
 				}
@@ -161,7 +169,7 @@ func (s Standard) Deploy(appDeploy AppDeploy) (AppDeployResponse, error) {
 					if appDeploy.Path == "" {
 						return ctx, nil
 					}
-					pkg, _, err = s.bitsManager.CreateAndUploadBitsPackage(appResp.App.GUID, appDeploy.Path, appDeploy.StageTimeout)
+					pkg, _, err = s.bitsManager.CreateAndUploadBitsPackage(appResp.App.GUID, appDeploy.Path, appDeploy.PathDownload, appDeploy.StageTimeout)
 				}
 				if err != nil {
 					return ctx, err
@@ -249,9 +257,14 @@ func (s Standard) Restage(appDeploy AppDeploy) (AppDeployResponse, error) {
 	}
 
 	// Stage the package
-	build, _, err := s.client.CreateBuild(resources.Build{
-		PackageGUID: packages[0].GUID,
-	})
+	var build resources.Build
+	if appDeploy.IsCNB() {
+		build, err = createCNBBuild(s.rawClient, appDeploy.App, packages[0].GUID, appDeploy.CNBCredentials)
+	} else {
+		build, _, err = s.client.CreateBuild(resources.Build{
+			PackageGUID: packages[0].GUID,
+		})
+	}
 	if err != nil {
 		return AppDeployResponse{}, err
 	}
@@ -33,6 +33,7 @@ const (
 	stackMetadata         metadataType = "stacks"
 	segmentMetadata       metadataType = "isolation_segments"
 	serviceBrokerMetadata metadataType = "service_brokers"
+	domainMetadata        metadataType = "domains"
 )
 
 func labelsSchema() *schema.Schema {
@@ -76,6 +76,12 @@ func Provider() *schema.Provider {
 				Description: "Number of logs message which can be see when app creation is errored (-1 means all messages stored)",
 				DefaultFunc: schema.EnvDefaultFunc("CF_APP_LOGS_MAX", 30),
 			},
+			"app_logs_dir": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Directory where staging and startup logs streamed during app deployment are written, in a file per app",
+				DefaultFunc: schema.EnvDefaultFunc("CF_APP_LOGS_DIR", ""),
+			},
 			"purge_when_delete": &schema.Schema{
 				Type:        schema.TypeBool,
 				Optional:    true,
@@ -105,8 +111,10 @@ func Provider() *schema.Provider {
 		DataSourcesMap: map[string]*schema.Resource{
 			"cloudfoundry_info":                  dataSourceInfo(),
 			"cloudfoundry_stack":                 dataSourceStack(),
+			"cloudfoundry_buildpack":             dataSourceBuildpack(),
 			"cloudfoundry_router_group":          dataSourceRouterGroup(),
 			"cloudfoundry_user":                  dataSourceUser(),
+			"cloudfoundry_uaa_client":            dataSourceUAAClient(),
 			"cloudfoundry_domain":                dataSourceDomain(),
 			"cloudfoundry_route":                 dataSourceRoute(),
 			"cloudfoundry_asg":                   dataSourceAsg(),
@@ -119,12 +127,17 @@ func Provider() *schema.Provider {
 			"cloudfoundry_user_provided_service": dataSourceUserProvidedService(),
 			"cloudfoundry_service_key":           dataSourceServiceKey(),
 			"cloudfoundry_service":               dataSourceService(),
+			"cloudfoundry_service_offerings":     dataSourceServiceOfferings(),
+			"cloudfoundry_service_plans":         dataSourceServicePlans(),
 			"cloudfoundry_app":                   dataSourceApp(),
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
 			"cloudfoundry_feature_flags":                 resourceConfig(),
 			"cloudfoundry_user":                          resourceUser(),
+			"cloudfoundry_uaa_client":                    resourceUAAClient(),
+			"cloudfoundry_uaa_group":                     resourceUAAGroup(),
+			"cloudfoundry_uaa_group_members":             resourceUAAGroupMembers(),
 			"cloudfoundry_domain":                        resourceDomain(),
 			"cloudfoundry_private_domain_access":         resourcePrivateDomainAccess(),
 			"cloudfoundry_asg":                           resourceAsg(),
@@ -169,6 +182,7 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData) (interface{}
 		UaaClientSecret:           d.Get("uaa_client_secret").(string),
 		SkipSslValidation:         d.Get("skip_ssl_validation").(bool),
 		AppLogsMax:                d.Get("app_logs_max").(int),
+		AppLogsDir:                d.Get("app_logs_dir").(string),
 		DefaultQuotaName:          d.Get("default_quota_name").(string),
 		StoreTokensPath:           d.Get("store_tokens_path").(string),
 		ForceNotFailBrokerCatalog: d.Get("force_broker_not_fail_when_catalog_not_accessible").(bool),
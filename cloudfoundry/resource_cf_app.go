@@ -3,6 +3,7 @@ package cloudfoundry
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
@@ -16,8 +17,10 @@ import (
 	"code.cloudfoundry.org/cli/resources"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/hashcode"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/v3appdeployers"
 )
 
@@ -93,6 +96,38 @@ func resourceApp() *schema.Resource {
 				Elem:          &schema.Schema{Type: schema.TypeString},
 				ConflictsWith: []string{"buildpack"},
 			},
+			"lifecycle": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				Computed:      true,
+				Description:   "Lifecycle used to stage the app, either buildpack or cnb for cloud native buildpacks, docker is set when using docker_image",
+				ValidateFunc:  validation.StringInSlice([]string{string(constant.AppLifecycleTypeBuildpack), string(v3appdeployers.AppLifecycleTypeCNB)}, false),
+				ConflictsWith: []string{"docker_image", "docker_credentials"},
+			},
+			"cnb_credentials": &schema.Schema{
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Registry credentials used by cnb lifecycle to fetch buildpacks",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"registry": &schema.Schema{
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.NoZeroValues,
+						},
+						"username": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"password": &schema.Schema{
+							Type:      schema.TypeString,
+							Required:  true,
+							Sensitive: true,
+						},
+					},
+				},
+			},
 			"command": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
@@ -124,18 +159,28 @@ func resourceApp() *schema.Resource {
 			"path": &schema.Schema{
 				Type:          schema.TypeString,
 				Optional:      true,
-				Description:   "Path to an app zip in the form of unix path or http url",
+				Description:   "Path to an app zip or an app directory in the form of unix path, or to an app zip in the form of http url",
 				ConflictsWith: []string{"docker_image", "docker_credentials"},
 			},
+			"path_sha256":      pathSHA256Schema(),
+			"path_headers":     pathHeadersSchema(),
+			"path_credentials": pathCredentialsSchema(),
 			"source_code_hash": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				Description: "Hash triggering app redeployment when changed, computed from files contents when path is a directory",
 			},
 			"docker_image": &schema.Schema{
 				Type:          schema.TypeString,
 				Optional:      true,
 				ConflictsWith: []string{"path"},
 			},
+			"docker_image_digest": &schema.Schema{
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Digest docker_image is resolved to on the registry when deploying, the app is deployed pinned to it",
+			},
 			"docker_credentials": &schema.Schema{
 				Type:          schema.TypeMap,
 				Optional:      true,
@@ -256,6 +301,21 @@ func resourceApp() *schema.Resource {
 					return diff.ForceNew("path")
 				}
 			}
+			if err := appDirectoryHashCustomizeDiff(diff); err != nil {
+				return err
+			}
+			if err := dockerImageDigestCustomizeDiff(diff); err != nil {
+				return err
+			}
+			if creds := diff.Get("cnb_credentials").(*schema.Set); creds.Len() > 0 &&
+				diff.Get("lifecycle").(string) != string(v3appdeployers.AppLifecycleTypeCNB) {
+				return fmt.Errorf("cnb_credentials can only be used with lifecycle cnb")
+			}
+			if diff.HasChange("service_binding") {
+				if err := validateAppServiceBindingParams(ctx, session, diff); err != nil {
+					return err
+				}
+			}
 			if diff.Id() == "" {
 				return nil
 			}
@@ -271,11 +331,45 @@ func resourceApp() *schema.Resource {
 				diff.ForceNew("stack")
 			}
 
+			// cloud controller does not allow to change lifecycle type of an app
+			if diff.HasChange("lifecycle") && diff.NewValueKnown("lifecycle") && !deployer.IsCreateNewApp() {
+				diff.ForceNew("lifecycle")
+			}
+
 			return nil
 		},
 	}
 }
 
+// validateAppServiceBindingParams validates binding params against the binding schema of each service instance plan
+func validateAppServiceBindingParams(ctx context.Context, session *managers.Session, diff *schema.ResourceDiff) error {
+	for i, b := range diff.Get("service_binding").([]interface{}) {
+		prefix := fmt.Sprintf("service_binding.%d.", i)
+		if !diff.NewValueKnown(prefix+"service_instance") || !diff.NewValueKnown(prefix+"params") || !diff.NewValueKnown(prefix+"params_json") {
+			continue
+		}
+		binding := b.(map[string]interface{})
+		paramsMap, _ := binding["params"].(map[string]interface{})
+		paramsJSON, _ := binding["params_json"].(string)
+		params, ok, err := serviceParamsFromConfig(paramsMap, paramsJSON)
+		if err != nil || !ok {
+			continue
+		}
+		planGUID, err := serviceInstancePlanGUID(ctx, session, binding["service_instance"].(string))
+		if err != nil || planGUID == "" {
+			continue
+		}
+		attr := prefix + "params"
+		if len(paramsMap) == 0 {
+			attr = prefix + "params_json"
+		}
+		if err := validateServiceParams(ctx, session, planGUID, servicePlanSchemaBindingCreate, attr, params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func validateAppV3HealthCheckType(v interface{}, k string) (ws []string, errs []error) {
 	value := v.(string)
 	if value != "port" && value != "process" && value != "http" && value != "none" {
@@ -302,6 +396,9 @@ func resourceAppCreate(ctx context.Context, d *schema.ResourceData, meta interfa
 	deployer := session.V3Deployer.Strategy(d.Get("strategy").(string))
 	log.Printf("[INFO] Use deploy strategy %s", deployer.Names()[0])
 
+	if digest, ok := resolveDockerImageDigest(session, d); ok {
+		_ = d.Set("docker_image_digest", digest)
+	}
 	appDeploy, err := ResourceDataToAppDeployV3(d)
 	if err != nil {
 		return diag.FromErr(err)
@@ -309,7 +406,7 @@ func resourceAppCreate(ctx context.Context, d *schema.ResourceData, meta interfa
 
 	appResp, err := deployer.Deploy(appDeploy)
 	if err != nil {
-		return diag.FromErr(err)
+		return appDeployDiags(err)
 	}
 
 	// Ports are set to 8080 by default
@@ -406,6 +503,10 @@ func resourceAppRead(ctx context.Context, d *schema.ResourceData, meta interface
 		appDeployResponse.Ports = []int{DefaultAppPort}
 	}
 
+	// a tag moved to another image since deployment is found by resolving it again
+	if digest, ok := resolveDockerImageDigest(session, d); ok {
+		_ = d.Set("docker_image_digest", digest)
+	}
 	AppDeployV3ToResourceData(d, appDeployResponse)
 
 	err = metadataRead(appMetadata, d, meta, false)
@@ -436,6 +537,11 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	}
 	d.Set("ports", finalPorts)
 
+	if d.HasChange("docker_image") {
+		digest, _ := resolveDockerImageDigest(session, d)
+		_ = d.Set("docker_image_digest", digest)
+	}
+
 	// Parse tfstate to appDeploy struct for deployer
 	appDeploy, err := ResourceDataToAppDeployV3(d)
 	if err != nil {
@@ -448,7 +554,7 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	if IsAppCodeChange(d) || (deployer.IsCreateNewApp() && IsAppRestartNeeded(d)) || (deployer.IsCreateNewApp() && IsAppRestageNeeded(d)) {
 		appResp, err := deployer.Deploy(appDeploy)
 		if err != nil {
-			return diag.FromErr(err)
+			return appDeployDiags(err)
 		}
 		d.Partial(false)
 		AppDeployV3ToResourceData(d, appResp)
@@ -490,6 +596,14 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	if d.HasChange("stack") {
 		appUpdate.StackName = d.Get("stack").(string)
 	}
+	// cnb lifecycle must be sent entirely through raw client, cli client does not know it
+	cnbUpdateRequired := appDeploy.IsCNB() &&
+		(d.HasChange("buildpacks") || d.HasChange("stack") || d.HasChange("cnb_credentials"))
+	if cnbUpdateRequired {
+		appUpdate.LifecycleType = appDeploy.App.LifecycleType
+		appUpdate.LifecycleBuildpacks = appDeploy.App.LifecycleBuildpacks
+		appUpdate.StackName = appDeploy.App.StackName
+	}
 
 	// Ports - custom ports are not yet supported in v3 provider
 	if d.HasChange("ports") {
@@ -534,7 +648,7 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	}
 
 	if d.HasChange("docker_image") {
-		appDeploy.AppPackage.DockerImage = d.Get("docker_image").(string)
+		appDeploy.AppPackage.DockerImage = dockerPackageImage(d)
 		if v, ok := d.GetOk("docker_credentials"); ok {
 			vv := v.(map[string]interface{})
 			appDeploy.AppPackage.DockerUsername = vv["username"].(string)
@@ -546,7 +660,12 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 	if IsAppUpdateOnly(d) || (IsAppRestageNeeded(d) && !deployer.IsCreateNewApp()) || (IsAppRestartNeeded(d) && !deployer.IsCreateNewApp()) {
 		log.Printf("\n--------------\n Updating app \n--------------\n")
 		// Update application
-		app, _, err := session.ClientV3.UpdateApplication(appUpdate)
+		var app resources.Application
+		if cnbUpdateRequired {
+			app, err = v3appdeployers.CreateOrUpdateCNBApplication(session.RawClient, appUpdate, appDeploy.CNBCredentials)
+		} else {
+			app, _, err = session.ClientV3.UpdateApplication(appUpdate)
+		}
 		if err != nil {
 			return diag.FromErr(err)
 		}
@@ -653,7 +772,7 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 
 		appResp, err := deployer.Restage(appDeploy)
 		if err != nil {
-			return diag.FromErr(err)
+			return appDeployDiags(err)
 		}
 		d.Partial(false)
 		AppDeployV3ToResourceData(d, appResp)
@@ -670,7 +789,7 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 			_, _, err = session.V3RunBinder.Restart(appDeploy)
 		}
 		if err != nil {
-			return diag.FromErr(err)
+			return appDeployDiags(err)
 		}
 
 		d.Partial(false)
@@ -686,7 +805,8 @@ func resourceAppUpdate(ctx context.Context, d *schema.ResourceData, meta interfa
 }
 
 func IsAppCodeChange(d ResourceChanger) bool {
-	return d.HasChange("path") || d.HasChange("source_code_hash") || d.HasChange("docker_image")
+	return d.HasChange("path") || d.HasChange("path_sha256") || d.HasChange("source_code_hash") ||
+		d.HasChange("docker_image")
 }
 
 func IsAppUpdateOnly(d ResourceChanger) bool {
@@ -694,11 +814,11 @@ func IsAppUpdateOnly(d ResourceChanger) bool {
 		return false
 	}
 	return d.HasChange("name") || d.HasChange("instances") ||
-		d.HasChange("enable_ssh") || d.HasChange("stopped")
+		d.HasChange("enable_ssh") || d.HasChange("stopped") || d.HasChange("cnb_credentials")
 }
 
 func IsAppRestageNeeded(d ResourceChanger) bool {
-	return d.HasChange("buildpack") || d.HasChange("buildpacks") || d.HasChange("stack") ||
+	return d.HasChange("buildpack") || d.HasChange("buildpacks") || d.HasChange("stack") || d.HasChange("lifecycle") ||
 		d.HasChange("service_binding") || d.HasChange("environment")
 }
 
@@ -749,3 +869,73 @@ func resourceAppDelete(ctx context.Context, d *schema.ResourceData, meta interfa
 	err = v3appdeployers.SafeAppDeletion(*session.ClientV3, appGUID, 5)
 	return diag.FromErr(err)
 }
+
+// appDirectoryHashCustomizeDiff sets source_code_hash from files of the app directory given in path,
+// unless source_code_hash is given in configuration, so that the app is only redeployed when files change
+func appDirectoryHashCustomizeDiff(diff *schema.ResourceDiff) error {
+	path := diff.Get("path").(string)
+	if path == "" || !bits.IsDirectory(path) || !diff.GetRawConfig().GetAttr("source_code_hash").IsNull() {
+		return nil
+	}
+	files, err := bits.GatherDirectory(strings.TrimPrefix(path, "file://"))
+	if err != nil {
+		return err
+	}
+	hash := bits.DirectoryHash(files)
+	if hash == diff.Get("source_code_hash").(string) {
+		return nil
+	}
+	return diff.SetNew("source_code_hash", hash)
+}
+
+// dockerImageDigestCustomizeDiff plans docker_image_digest to be known once the new docker_image is resolved on the
+// registry when applying, resolving it when planning could give another digest when applying if the tag is moved meanwhile
+func dockerImageDigestCustomizeDiff(diff *schema.ResourceDiff) error {
+	if diff.NewValueKnown("docker_image") && !diff.HasChange("docker_image") {
+		return nil
+	}
+	if diff.NewValueKnown("docker_image") && diff.Get("docker_image").(string) == "" {
+		return diff.SetNew("docker_image_digest", "")
+	}
+	return diff.SetNewComputed("docker_image_digest")
+}
+
+// resolveDockerImageDigest gives the digest the tag of docker_image points to on the registry,
+// false is given when there is no docker image or when the registry can't be reached
+func resolveDockerImageDigest(session *managers.Session, d *schema.ResourceData) (string, bool) {
+	image := d.Get("docker_image").(string)
+	if image == "" {
+		return "", false
+	}
+	creds := d.Get("docker_credentials").(map[string]interface{})
+	username, _ := creds["username"].(string)
+	password, _ := creds["password"].(string)
+	digest, err := session.RegistryClient.ResolveDigest(image, username, password)
+	if err != nil {
+		log.Printf("[WARN] digest of docker image %s can't be resolved, the app is not pinned to a new digest: %s", image, err.Error())
+		return "", false
+	}
+	return digest, true
+}
+
+// appDeployDiags reports a failed deployment with its details (logs, crashed instances...) as separate diagnostics
+func appDeployDiags(err error) diag.Diagnostics {
+	var deployErr *common.DeployError
+	if !errors.As(err, &deployErr) {
+		return diag.FromErr(err)
+	}
+	diags := diag.Diagnostics{
+		diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  deployErr.Err.Error(),
+		},
+	}
+	for _, detail := range deployErr.Details {
+		diags = append(diags, diag.Diagnostic{
+			Severity: diag.Error,
+			Summary:  detail.Summary,
+			Detail:   detail.Detail,
+		})
+	}
+	return diags
+}
@@ -2,6 +2,8 @@ package cloudfoundry
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"testing"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -461,6 +463,7 @@ var appPaths = []struct {
 	path       string
 }{
 	{typeOfPath: "local", path: asset("dummy-app.zip")},
+	{typeOfPath: "directory", path: filepath.Join(testDir(), "dummy-app")},
 	{typeOfPath: "remote", path: "https://raw.githubusercontent.com/cloudfoundry-community/terraform-provider-cloudfoundry/main/tests/cf-acceptance-tests/assets/dummy-app.zip"},
 }
 
@@ -1061,8 +1064,15 @@ func TestAccResApp_dockerApp(t *testing.T) {
 							refApp, "enable_ssh", "true"),
 						resource.TestCheckResourceAttr(
 							refApp, "docker_image", "cloudfoundry/diego-docker-app:latest"),
+						resource.TestMatchResourceAttr(
+							refApp, "docker_image_digest", regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)),
 					),
 				},
+				resource.TestStep{
+					// image is pinned to the digest, tag is kept in state
+					Config:   fmt.Sprintf(appResourceDocker, defaultAppDomain(), orgName, spaceName),
+					PlanOnly: true,
+				},
 			},
 		})
 }
@@ -1425,3 +1435,123 @@ func TestAccMultipleBuildpacks(t *testing.T) {
 			},
 		})
 }
+
+const appResourceCNB = `
+data "cloudfoundry_org" "org" {
+	name = "%s"
+}
+data "cloudfoundry_space" "space" {
+	name = "%s"
+	org = "${data.cloudfoundry_org.org.id}"
+}
+resource "cloudfoundry_app" "app_cnb" {
+	name = "app-cnb"
+	space = "${data.cloudfoundry_space.space.id}"
+	lifecycle = "cnb"
+	buildpacks = ["docker://gcr.io/paketo-buildpacks/procfile"]
+	command = "./dummy-app"
+	cnb_credentials {
+		registry = "gcr.io"
+		username = "user"
+		password = "password"
+	}
+
+	path = "%s"
+	strategy = "%s"
+}
+`
+
+func TestAccResApp_cnbLifecycle(t *testing.T) {
+
+	_, orgName := defaultTestOrg(t)
+	_, spaceName := defaultTestSpace(t)
+	refApp := "cloudfoundry_app.app_cnb"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckAppDestroyed([]string{"app-cnb"}),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(appResourceCNB, orgName, spaceName, appPath, "standard"),
+					Check: resource.ComposeTestCheckFunc(
+						func(s *terraform.State) error {
+							session := testAccProvider.Meta().(*managers.Session)
+							id := s.RootModule().Resources[refApp].Primary.ID
+							apps, _, err := session.ClientV3.GetApplications(ccv3.Query{
+								Key:    ccv3.GUIDFilter,
+								Values: []string{id},
+							})
+							if err != nil {
+								return err
+							}
+							if len(apps) == 0 {
+								return NotFound
+							}
+							return assertSame(string(apps[0].LifecycleType), "cnb")
+						},
+						resource.TestCheckResourceAttr(refApp, "lifecycle", "cnb"),
+						resource.TestCheckResourceAttr(refApp, "buildpacks.#", "1"),
+						resource.TestCheckResourceAttr(refApp, "buildpacks.0", "docker://gcr.io/paketo-buildpacks/procfile"),
+						resource.TestCheckResourceAttr(refApp, "cnb_credentials.#", "1"),
+					),
+				},
+				resource.TestStep{
+					Config:   fmt.Sprintf(appResourceCNB, orgName, spaceName, appPath, "standard"),
+					PlanOnly: true,
+				},
+			},
+		})
+}
+
+func TestDockerImageState(t *testing.T) {
+	const (
+		digest      = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		movedDigest = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	cases := map[string]struct {
+		configured string
+		resolved   string
+		deployed   string
+		image      string
+		digest     string
+	}{
+		"pinned_tag_not_moved": {
+			configured: "redis:4.0", resolved: digest, deployed: "redis@" + digest,
+			image: "redis:4.0", digest: digest,
+		},
+		"pinned_tag_not_resolved": {
+			configured: "redis:4.0", deployed: "redis@" + digest,
+			image: "redis:4.0", digest: digest,
+		},
+		"pinned_tag_moved": {
+			configured: "redis:4.0", resolved: movedDigest, deployed: "redis@" + digest,
+			image: "redis:4.0@" + digest, digest: digest,
+		},
+		"moved_tag_kept_until_deployed": {
+			configured: "redis:4.0@" + digest, resolved: digest, deployed: "redis@" + digest,
+			image: "redis:4.0@" + digest, digest: digest,
+		},
+		"unpinned_digest_recorded": {
+			configured: "redis:4.0", resolved: digest, deployed: "redis:4.0",
+			image: "redis:4.0", digest: digest,
+		},
+		"other_image_deployed": {
+			configured: "redis:4.0", resolved: digest, deployed: "redis:5.0",
+			image: "redis:5.0",
+		},
+		"imported": {
+			deployed: "redis@" + digest,
+			image:    "redis@" + digest, digest: digest,
+		},
+	}
+
+	for tn, tc := range cases {
+		image, digest := dockerImageState(tc.configured, tc.resolved, tc.deployed)
+		if image != tc.image || digest != tc.digest {
+			t.Fatalf("bad: %s, expected %q %q, got %q %q", tn, tc.image, tc.digest, image, digest)
+		}
+	}
+}
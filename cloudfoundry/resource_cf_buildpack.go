@@ -1,13 +1,40 @@
 package cloudfoundry
 
 import (
-	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/resources"
 	"context"
+	"fmt"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+	"net/http"
+	"net/url"
 )
 
+const (
+	buildpackLifecycleBuildpack = "buildpack"
+	buildpackLifecycleCNB       = "cnb"
+)
+
+// buildpackV3 is a v3 buildpack as returned by cloud controller,
+// lifecycle is not handled by cli client so requests are made through raw client
+type buildpackV3 struct {
+	GUID      string  `json:"guid,omitempty"`
+	Name      string  `json:"name,omitempty"`
+	Stack     *string `json:"stack,omitempty"`
+	Lifecycle string  `json:"lifecycle,omitempty"`
+	Position  *int    `json:"position,omitempty"`
+	Enabled   *bool   `json:"enabled,omitempty"`
+	Locked    *bool   `json:"locked,omitempty"`
+	Filename  *string `json:"filename,omitempty"`
+}
+
+type buildpacksV3 struct {
+	Resources []buildpackV3 `json:"resources"`
+}
+
 func resourceBuildpack() *schema.Resource {
 	return &schema.Resource{
 
@@ -19,7 +46,7 @@ func resourceBuildpack() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			StateContext: ImportReadContext(resourceBuildpackRead),
 		},
-		SchemaVersion: 3,
+		SchemaVersion: 4,
 		MigrateState:  resourceBuildpackMigrateState,
 		Schema: map[string]*schema.Schema{
 
@@ -28,6 +55,21 @@ func resourceBuildpack() *schema.Resource {
 				Required: true,
 				ForceNew: true,
 			},
+			"stack": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Computed:    true,
+				ForceNew:    true,
+				Description: "Name of the stack the buildpack is registered for, buildpacks with the same name can exist for different stacks",
+			},
+			"lifecycle": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				Default:      buildpackLifecycleBuildpack,
+				ValidateFunc: validation.StringInSlice([]string{buildpackLifecycleBuildpack, buildpackLifecycleCNB}, false),
+				Description:  "Lifecycle of the buildpack, either buildpack or cnb for cloud native buildpacks",
+			},
 			"position": &schema.Schema{
 				Type:     schema.TypeInt,
 				Optional: true,
@@ -46,8 +88,11 @@ func resourceBuildpack() *schema.Resource {
 			"path": &schema.Schema{
 				Type:        schema.TypeString,
 				Required:    true,
-				Description: "Path to a buildpack zip in the form of unix path or http url",
+				Description: "Path to a buildpack zip (or cnb/tgz for cnb lifecycle) in the form of unix path or http url",
 			},
+			"path_sha256":      pathSHA256Schema(),
+			"path_headers":     pathHeadersSchema(),
+			"path_credentials": pathCredentialsSchema(),
 			"source_code_hash": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -72,28 +117,39 @@ func resourceBuildpackCreate(ctx context.Context, d *schema.ResourceData, meta i
 	enabled := d.Get("enabled").(bool)
 	path := d.Get("path").(string)
 
-	bp, _, err := session.ClientV2.CreateBuildpack(ccv2.Buildpack{
-		Name:     name,
-		Enabled:  BoolToNullBool(enabled),
-		Locked:   BoolToNullBool(locked),
-		Position: IntToNullInt(position),
-	})
+	bpCreate := buildpackV3{
+		Name:    name,
+		Enabled: &enabled,
+		Locked:  &locked,
+	}
+	if position > 0 {
+		bpCreate.Position = &position
+	}
+	if stack, ok := d.GetOk("stack"); ok {
+		s := stack.(string)
+		bpCreate.Stack = &s
+	}
+	// lifecycle is only sent when not default to stay compatible with cloud controller not knowing it
+	if lifecycle := d.Get("lifecycle").(string); lifecycle != buildpackLifecycleBuildpack {
+		bpCreate.Lifecycle = lifecycle
+	}
+
+	var bp buildpackV3
+	_, err := rawJSONRequest(session, http.MethodPost, "/v3/buildpacks", bpCreate, &bp)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	err = session.BitsManager.UploadBuildpack(bp.GUID, path)
+	d.SetId(bp.GUID)
+
+	err = session.BitsManager.UploadBuildpack(bp.GUID, path, d.Get("lifecycle").(string), pathDownloadOptions(d))
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	bp, _, err = session.ClientV2.GetBuildpack(bp.GUID)
+	bp, err = getBuildpackV3(session, bp.GUID)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	d.SetId(bp.GUID)
-	d.Set("position", bp.Position.Value)
-	d.Set("enabled", bp.Enabled.Value)
-	d.Set("locked", bp.Locked.Value)
-	d.Set("filename", bp.Filename)
+	setBuildpackV3State(d, bp)
 
 	err = metadataCreate(buildpackMetadata, d, meta)
 	if err != nil {
@@ -106,7 +162,7 @@ func resourceBuildpackRead(ctx context.Context, d *schema.ResourceData, meta int
 
 	session := meta.(*managers.Session)
 
-	bp, _, err := session.ClientV2.GetBuildpack(d.Id())
+	bp, err := getBuildpackV3(session, d.Id())
 	if err != nil {
 		if IsErrNotFound(err) {
 			d.SetId("")
@@ -116,10 +172,7 @@ func resourceBuildpackRead(ctx context.Context, d *schema.ResourceData, meta int
 	}
 
 	d.Set("name", bp.Name)
-	d.Set("position", bp.Position.Value)
-	d.Set("enabled", bp.Enabled.Value)
-	d.Set("locked", bp.Locked.Value)
-	d.Set("filename", bp.Filename)
+	setBuildpackV3State(d, bp)
 
 	err = metadataRead(buildpackMetadata, d, meta, false)
 	if err != nil {
@@ -139,7 +192,7 @@ func resourceBuildpackUpdate(ctx context.Context, d *schema.ResourceData, meta i
 		position := d.Get("position").(int)
 		locked := d.Get("locked").(bool)
 		enabled := d.Get("enabled").(bool)
-		_, _, err := session.ClientV2.UpdateBuildpack(ccv2.Buildpack{
+		_, _, err := session.ClientV3.UpdateBuildpack(resources.Buildpack{
 			GUID:     d.Id(),
 			Name:     name,
 			Enabled:  BoolToNullBool(enabled),
@@ -151,8 +204,8 @@ func resourceBuildpackUpdate(ctx context.Context, d *schema.ResourceData, meta i
 		}
 	}
 
-	if d.HasChange("path") || d.HasChange("source_code_hash") || d.HasChange("filename") {
-		err := session.BitsManager.UploadBuildpack(d.Id(), d.Get("path").(string))
+	if d.HasChange("path") || d.HasChange("path_sha256") || d.HasChange("source_code_hash") || d.HasChange("filename") {
+		err := session.BitsManager.UploadBuildpack(d.Id(), d.Get("path").(string), d.Get("lifecycle").(string), pathDownloadOptions(d))
 		if err != nil {
 			return diag.FromErr(err)
 		}
@@ -167,6 +220,62 @@ func resourceBuildpackUpdate(ctx context.Context, d *schema.ResourceData, meta i
 func resourceBuildpackDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
-	_, err := session.ClientV2.DeleteBuildpack(d.Id())
+	jobURL, _, err := session.ClientV3.DeleteBuildpack(d.Id())
+	if err != nil {
+		if IsErrNotFound(err) {
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	_, err = session.ClientV3.PollJob(jobURL)
 	return diag.FromErr(err)
 }
+
+func getBuildpackV3(session *managers.Session, guid string) (buildpackV3, error) {
+	var bp buildpackV3
+	_, err := rawJSONRequest(session, http.MethodGet, fmt.Sprintf("/v3/buildpacks/%s", guid), nil, &bp)
+	return bp, err
+}
+
+// listBuildpacksV3 returns buildpacks matching given names, stacks and lifecycle, empty filters are ignored
+func listBuildpacksV3(session *managers.Session, name string, stack string, lifecycle string) ([]buildpackV3, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set(string(ccv3.NameFilter), name)
+	}
+	if stack != "" {
+		query.Set(string(ccv3.StackFilter), stack)
+	}
+	if lifecycle != "" {
+		query.Set("lifecycle", lifecycle)
+	}
+	var bps buildpacksV3
+	_, err := rawJSONRequest(session, http.MethodGet, "/v3/buildpacks?"+query.Encode(), nil, &bps)
+	return bps.Resources, err
+}
+
+func setBuildpackV3State(d *schema.ResourceData, bp buildpackV3) {
+	stack := ""
+	if bp.Stack != nil {
+		stack = *bp.Stack
+	}
+	d.Set("stack", stack)
+	// cloud controller not supporting cnb does not return lifecycle
+	lifecycle := bp.Lifecycle
+	if lifecycle == "" {
+		lifecycle = buildpackLifecycleBuildpack
+	}
+	d.Set("lifecycle", lifecycle)
+	if bp.Position != nil {
+		d.Set("position", *bp.Position)
+	}
+	if bp.Enabled != nil {
+		d.Set("enabled", *bp.Enabled)
+	}
+	if bp.Locked != nil {
+		d.Set("locked", *bp.Locked)
+	}
+	if bp.Filename != nil {
+		d.Set("filename", *bp.Filename)
+	}
+}
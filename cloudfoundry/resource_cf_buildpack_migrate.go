@@ -10,11 +10,22 @@ func resourceBuildpackMigrateState(
 	v int, is *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
 	switch v {
 	case 0:
-		log.Println("[INFO] Found buildpack Record State v0; migrating from v0 to v3")
-		return migrateBuildpackStateV2toV3(is, meta)
+		log.Println("[INFO] Found buildpack Record State v0; migrating from v0 to v4")
+		is, err := migrateBuildpackStateV2toV3(is, meta)
+		if err != nil {
+			return is, err
+		}
+		return migrateBuildpackStateV3toV4(is)
 	case 2:
-		log.Println("[INFO] Found buildpack Record State v2; migrating from v2 to v3")
-		return migrateBuildpackStateV2toV3(is, meta)
+		log.Println("[INFO] Found buildpack Record State v2; migrating from v2 to v4")
+		is, err := migrateBuildpackStateV2toV3(is, meta)
+		if err != nil {
+			return is, err
+		}
+		return migrateBuildpackStateV3toV4(is)
+	case 3:
+		log.Println("[INFO] Found buildpack Record State v3; migrating from v3 to v4")
+		return migrateBuildpackStateV3toV4(is)
 	default:
 		return is, fmt.Errorf("Unexpected schema version: %d", v)
 	}
@@ -28,3 +39,16 @@ func migrateBuildpackStateV2toV3(is *terraform.InstanceState, meta interface{})
 
 	return migrateBitsStateV2toV3(is, meta)
 }
+
+// migrateBuildpackStateV3toV4 sets lifecycle introduced in v4, all previous buildpacks were using buildpack lifecycle,
+// stack is computed and will be filled on next read
+func migrateBuildpackStateV3toV4(is *terraform.InstanceState) (*terraform.InstanceState, error) {
+	if is.Empty() {
+		log.Println("[DEBUG] Empty InstanceState; nothing to migrate.")
+		return is, nil
+	}
+	if _, ok := is.Attributes["lifecycle"]; !ok {
+		is.Attributes["lifecycle"] = buildpackLifecycleBuildpack
+	}
+	return is, nil
+}
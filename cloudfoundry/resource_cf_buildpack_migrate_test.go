@@ -124,3 +124,50 @@ func TestBuildpackMigrateStateV0toV3(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildpackMigrateStateV3toV4(t *testing.T) {
+	cases := map[string]struct {
+		Attributes map[string]string
+		Expected   map[string]string
+	}{
+		"v3_4_no_lifecycle": {
+			Attributes: map[string]string{
+				"name": "my-buildpack",
+				"path": "https://github.com/cloudfoundry-community/tomee-buildpack/releases/download/v4.5.2/tomee-buildpack-v4.5.2.zip",
+			},
+			Expected: map[string]string{
+				"name":      "my-buildpack",
+				"lifecycle": "buildpack",
+			},
+		},
+		"v3_4_lifecycle_kept": {
+			Attributes: map[string]string{
+				"name":      "my-cnb",
+				"lifecycle": "cnb",
+			},
+			Expected: map[string]string{
+				"name":      "my-cnb",
+				"lifecycle": "cnb",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		is := &terraform.InstanceState{
+			ID:         "a_buildpack",
+			Attributes: tc.Attributes,
+		}
+		is, err := resourceBuildpack().MigrateState(3, is, nil)
+		if err != nil {
+			t.Fatalf("bad: %s, err: %#v", tn, err)
+		}
+
+		for k, v := range tc.Expected {
+			if is.Attributes[k] != v {
+				t.Fatalf(
+					"bad: %s\n\n expected: %#v -> %#v\n got: %#v -> %#v\n in: %#v",
+					tn, k, v, k, is.Attributes[k], is.Attributes)
+			}
+		}
+	}
+}
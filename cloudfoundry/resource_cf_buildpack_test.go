@@ -1,8 +1,15 @@
 package cloudfoundry
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -47,6 +54,40 @@ resource "cloudfoundry_buildpack" "tomee" {
 }
 `
 
+const buildpackResourceStack = `
+
+data "cloudfoundry_stack" "s" {
+	name = "%s"
+}
+
+resource "cloudfoundry_buildpack" "tomee" {
+
+	name = "tomee-buildpack-res-stack"
+	stack = data.cloudfoundry_stack.s.name
+
+	path = "https://github.com/cloudfoundry-community/tomee-buildpack/releases/download/v4.3/tomee-buildpack-v4.3.zip"
+}
+
+data "cloudfoundry_buildpack" "tomee" {
+	name = cloudfoundry_buildpack.tomee.name
+	stack = cloudfoundry_buildpack.tomee.stack
+}
+`
+
+const buildpackResourceDownload = `
+
+resource "cloudfoundry_buildpack" "tomee" {
+
+	name = "tomee-buildpack-res-download"
+
+	path = "%s/tomee-buildpack-v4.5.2.zip"
+	path_sha256 = "%s"
+	path_credentials = {
+		token = "%s"
+	}
+}
+`
+
 func TestAccResBuildpack_normal(t *testing.T) {
 
 	fixturesBp := asset("buildpacks")
@@ -112,6 +153,99 @@ func TestAccResBuildpack_normal(t *testing.T) {
 		})
 }
 
+func TestAccResBuildpack_download(t *testing.T) {
+
+	bpPath := filepath.Join(asset("buildpacks"), "tomee-buildpack-v4.5.2.zip")
+	refBuildpack := "cloudfoundry_buildpack.tomee"
+	token := "test-acc-token"
+
+	content, err := os.ReadFile(bpPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, bpPath)
+	}))
+	defer server.Close()
+
+	resource.ParallelTest(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckBuildpackDestroyed("tomee-buildpack-res-download"),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config:      fmt.Sprintf(buildpackResourceDownload, server.URL, checksum, "wrong-token"),
+					ExpectError: regexp.MustCompile(`Failed to download file .* \(401 Unauthorized\)`),
+				},
+				resource.TestStep{
+					Config:      fmt.Sprintf(buildpackResourceDownload, server.URL, strings.Repeat("0", 64), token),
+					ExpectError: regexp.MustCompile(`checksum mismatch`),
+				},
+				resource.TestStep{
+					Config: fmt.Sprintf(buildpackResourceDownload, server.URL, checksum, token),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckBuildpackExists(refBuildpack, "tomee-buildpack-v4.5.2.zip"),
+						resource.TestCheckResourceAttr(
+							refBuildpack, "path_sha256", checksum),
+					),
+				},
+			},
+		})
+}
+
+func TestAccResBuildpack_stack(t *testing.T) {
+
+	stacks, _, err := testSession().ClientV2.GetStacks()
+	if err != nil {
+		panic(err)
+	}
+	refBuildpack := "cloudfoundry_buildpack.tomee"
+	refDataBuildpack := "data.cloudfoundry_buildpack.tomee"
+
+	resource.ParallelTest(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckBuildpackDestroyed("tomee-buildpack-res-stack"),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(buildpackResourceStack, stacks[0].Name),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckBuildpackExists(refBuildpack, "tomee-buildpack-v4.3.zip"),
+						resource.TestCheckResourceAttr(
+							refBuildpack, "stack", stacks[0].Name),
+						resource.TestCheckResourceAttr(
+							refBuildpack, "lifecycle", "buildpack"),
+						resource.TestCheckResourceAttrPair(
+							refDataBuildpack, "id", refBuildpack, "id"),
+						resource.TestCheckResourceAttr(
+							refDataBuildpack, "stack", stacks[0].Name),
+						resource.TestCheckResourceAttr(
+							refDataBuildpack, "lifecycle", "buildpack"),
+						resource.TestCheckResourceAttr(
+							refDataBuildpack, "filename", "tomee-buildpack-v4.3.zip"),
+					),
+				},
+				resource.TestStep{
+					ResourceName:            refBuildpack,
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"path"},
+				},
+			},
+		})
+}
+
 func testAccCheckBuildpackExists(refBuildpack, bpFilename string) resource.TestCheckFunc {
 
 	return func(s *terraform.State) error {
@@ -3,6 +3,8 @@ package cloudfoundry
 import (
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
 	"context"
+	"fmt"
+	goResource "github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
 	"strings"
@@ -16,6 +18,7 @@ func resourceDomain() *schema.Resource {
 
 		CreateContext: resourceDomainCreate,
 		ReadContext:   resourceDomainRead,
+		UpdateContext: resourceDomainUpdate,
 		DeleteContext: resourceDomainDelete,
 
 		Importer: &schema.ResourceImporter{
@@ -65,12 +68,16 @@ func resourceDomain() *schema.Resource {
 				Optional:      true,
 				ConflictsWith: []string{"router_group"},
 			},
-			// "shared-with": &schema.Schema{
-			// 	Type:     schema.TypeSet,
-			// 	Optional: true,
-			// 	Elem:     &schema.Schema{Type: schema.TypeString},
-			// 	Set:      resourceStringHash,
-			// },
+			"shared_orgs": &schema.Schema{
+				Type:          schema.TypeSet,
+				Optional:      true,
+				Elem:          &schema.Schema{Type: schema.TypeString},
+				Set:           resourceStringHash,
+				ConflictsWith: []string{"router_group"},
+				Description:   "Set of organization ids the private domain is shared with.",
+			},
+			labelsKey:      labelsSchema(),
+			annotationsKey: annotationsSchema(),
 		},
 	}
 }
@@ -109,6 +116,11 @@ func resourceDomainCreate(ctx context.Context, d *schema.ResourceData, meta inte
 		d.Set("name", subDomainAttr.(string)+"."+domainAttr.(string))
 	}
 
+	sharedOrgs := getStringsFromSet(d.Get("shared_orgs"))
+	if len(sharedOrgs) > 0 && !orgOk {
+		return diag.Errorf("the 'shared_orgs' attribute can only be set on a private domain, 'org' must be provided")
+	}
+
 	var (
 		ccDomain ccv2.Domain
 		err      error
@@ -126,6 +138,15 @@ func resourceDomainCreate(ctx context.Context, d *schema.ResourceData, meta inte
 	}
 	d.Set("router_type", ccDomain.RouterGroupType)
 	d.SetId(ccDomain.GUID)
+
+	err = updateDomainSharedOrgs(ctx, session, ccDomain.GUID, []string{}, sharedOrgs)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = metadataCreate(domainMetadata, d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	return nil
 }
 
@@ -158,6 +179,68 @@ func resourceDomainRead(ctx context.Context, d *schema.ResourceData, meta interf
 	d.Set("internal", ccDomain.Internal)
 	d.Set("org", ccDomain.OwningOrganizationGUID)
 
+	// shared organizations are only read when managed by this resource to not conflict
+	// with sharing made by cloudfoundry_private_domain_access
+	if _, ok := d.GetOk("shared_orgs"); ccDomain.OwningOrganizationGUID != "" && (ok || IsImportState(d)) {
+		domainV3, err := session.ClientGo.Domains.Get(ctx, id)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		sharedOrgs := make([]interface{}, 0)
+		if domainV3.Relationships.SharedOrganizations != nil {
+			for _, org := range domainV3.Relationships.SharedOrganizations.Data {
+				sharedOrgs = append(sharedOrgs, org.GUID)
+			}
+		}
+		d.Set("shared_orgs", schema.NewSet(resourceStringHash, sharedOrgs))
+	}
+
+	err = metadataRead(domainMetadata, d, meta, false)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceDomainUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	if d.HasChange("shared_orgs") {
+		if _, orgOk := d.GetOk("org"); !orgOk {
+			return diag.Errorf("the 'shared_orgs' attribute can only be set on a private domain, 'org' must be provided")
+		}
+		old, new := d.GetChange("shared_orgs")
+		err := updateDomainSharedOrgs(ctx, session, d.Id(), getStringsFromSet(old), getStringsFromSet(new))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return diag.FromErr(metadataUpdate(domainMetadata, d, meta))
+}
+
+// updateDomainSharedOrgs shares the private domain with added organizations and unshares it from removed ones
+func updateDomainSharedOrgs(ctx context.Context, session *managers.Session, domainGUID string, old []string, new []string) error {
+	add := make([]goResource.Relationship, 0)
+	for _, org := range new {
+		if !isInSlice(old, func(object interface{}) bool { return object.(string) == org }) {
+			add = append(add, goResource.Relationship{GUID: org})
+		}
+	}
+	if len(add) > 0 {
+		_, err := session.ClientGo.Domains.ShareMany(ctx, domainGUID, &goResource.ToManyRelationships{Data: add})
+		if err != nil {
+			return fmt.Errorf("Error when sharing domain '%s': %s", domainGUID, err.Error())
+		}
+	}
+	for _, org := range old {
+		if isInSlice(new, func(object interface{}) bool { return object.(string) == org }) {
+			continue
+		}
+		err := session.ClientGo.Domains.UnShare(ctx, domainGUID, org)
+		if err != nil && !IsErrNotFound(err) {
+			return fmt.Errorf("Error when unsharing domain '%s' from org '%s': %s", domainGUID, org, err.Error())
+		}
+	}
 	return nil
 }
 
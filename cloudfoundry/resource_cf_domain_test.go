@@ -40,6 +40,29 @@ resource "cloudfoundry_domain" "private" {
 }
 `
 
+const domainResourcePrivateShared = `
+
+resource "cloudfoundry_org" "owner" {
+  name = "domain-res-owner"
+}
+resource "cloudfoundry_org" "shared1" {
+  name = "domain-res-shared1"
+}
+resource "cloudfoundry_org" "shared2" {
+  name = "domain-res-shared2"
+}
+
+resource "cloudfoundry_domain" "private-shared" {
+  sub_domain = "private-shared"
+  domain = "%s"
+  org = "${cloudfoundry_org.owner.id}"
+  shared_orgs = [ %s ]
+  labels = {
+    team = "%s"
+  }
+}
+`
+
 func TestAccResSharedDomain_normal(t *testing.T) {
 
 	ref := "cloudfoundry_domain.shared"
@@ -227,3 +250,51 @@ func testAccCheckPrivateDomainDestroy(domainname string) resource.TestCheckFunc
 		return nil
 	}
 }
+
+func TestAccResPrivateDomain_sharedOrgs(t *testing.T) {
+
+	ref := "cloudfoundry_domain.private-shared"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckPrivateDomainDestroy("private-shared." + defaultAppDomain()),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(domainResourcePrivateShared,
+						defaultAppDomain(), `"${cloudfoundry_org.shared1.id}"`, "a"),
+					Check: resource.ComposeTestCheckFunc(
+						checkPrivateDomainExists(ref),
+						checkPrivateDomainShare("", ref, "cloudfoundry_org.shared1", true),
+						checkPrivateDomainShare("", ref, "cloudfoundry_org.shared2", false),
+						resource.TestCheckResourceAttr(
+							ref, "shared_orgs.#", "1"),
+						resource.TestCheckResourceAttr(
+							ref, "labels.team", "a"),
+					),
+				},
+
+				resource.TestStep{
+					Config: fmt.Sprintf(domainResourcePrivateShared,
+						defaultAppDomain(), `"${cloudfoundry_org.shared2.id}"`, "b"),
+					Check: resource.ComposeTestCheckFunc(
+						checkPrivateDomainExists(ref),
+						checkPrivateDomainShare("", ref, "cloudfoundry_org.shared1", false),
+						checkPrivateDomainShare("", ref, "cloudfoundry_org.shared2", true),
+						resource.TestCheckResourceAttr(
+							ref, "shared_orgs.#", "1"),
+						resource.TestCheckResourceAttr(
+							ref, "labels.team", "b"),
+					),
+				},
+
+				resource.TestStep{
+					ResourceName:      ref,
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		})
+}
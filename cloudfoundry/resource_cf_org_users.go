@@ -31,12 +31,15 @@ func resourceOrgUsers() *schema.Resource {
 			StateContext: ImportReadContext(resourceOrgUsersRead),
 		},
 
+		CustomizeDiff: rolesByUsernameCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"org": &schema.Schema{
 				Type:     schema.TypeString,
 				ForceNew: true,
 				Required: true,
 			},
+			"users_by_username": usersByUsernameSchema(orgRoleTypes),
 			"force": &schema.Schema{
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -101,6 +104,10 @@ func resourceOrgUsersRead(ctx context.Context, d *schema.ResourceData, meta inte
 		d.Set("org", d.Id())
 	}
 	session := meta.(*managers.Session)
+	byUsernameGUIDs, err := readRolesByUsername(session, d, roleScopeOrganization, d.Get("org").(string), orgRoleTypes)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	for t, r := range orgRoleMap {
 		users, _, err := session.ClientV2.GetOrganizationUsersByRole(r, d.Get("org").(string))
 		if err != nil {
@@ -108,6 +115,8 @@ func resourceOrgUsersRead(ctx context.Context, d *schema.ResourceData, meta inte
 		}
 		tfUsers := d.Get(t).(*schema.Set).List()
 		if d.Get("force").(bool) || IsImportState(d) {
+			// users given by username are managed in users_by_username
+			users = excludeUsersByGUID(users, byUsernameGUIDs[t])
 			usersByUsername := intersectSlices(tfUsers, users, func(source, item interface{}) bool {
 
 				return strings.EqualFold(source.(string), item.(ccv2.User).Username)
@@ -158,7 +167,8 @@ func resourceOrgUsersUpdate(ctx context.Context, d *schema.ResourceData, meta in
 			}
 		}
 	}
-	return nil
+	err := updateRolesByUsername(session, d, roleScopeOrganization, orgId, orgRoleTypes, "")
+	return diag.FromErr(err)
 }
 
 func resourceOrgUsersDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -173,7 +183,8 @@ func resourceOrgUsersDelete(ctx context.Context, d *schema.ResourceData, meta in
 			}
 		}
 	}
-	return nil
+	err := deleteRolesByUsername(session, d, roleScopeOrganization, orgId, orgRoleTypes)
+	return diag.FromErr(err)
 }
 
 func updateOrgUserByRole(session *managers.Session, role constant.UserRole, guid string, guidOrUsername string, byUsername bool) error {
@@ -2,6 +2,7 @@ package cloudfoundry
 
 import (
 	"fmt"
+	"regexp"
 	"testing"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -81,6 +82,17 @@ resource "cloudfoundry_org_users" "org_users1" {
 }
 `
 
+const orgUsersResourceByUsername = `
+resource "cloudfoundry_org_users" "org_users1" {
+	org = "%s"
+	users_by_username {
+		username = "%s"
+		origin = "uaa"
+		roles = [ %s ]
+	}
+}
+`
+
 func TestAccResOrgUsers_normal(t *testing.T) {
 	ref := "cloudfoundry_org_users.org_users1"
 	orgId, _ := defaultTestOrg(t)
@@ -172,6 +184,53 @@ func TestAccResOrgUsers_force(t *testing.T) {
 		})
 }
 
+func TestAccResOrgUsers_byUsername(t *testing.T) {
+	ref := "cloudfoundry_org_users.org_users1"
+	orgId, _ := defaultTestOrg(t)
+	usersMap := make(map[string][]ccv2.User)
+
+	// user only exists in uaa as a federated user before first login
+	sessions := testSession()
+	user, err := sessions.ClientUAA.CreateUser("test-acc-by-username@acme.com", "paasw0rd", "uaa")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		_ = sessions.ClientUAA.DeleteUser(user.ID)
+	}()
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			Steps: []resource.TestStep{
+				{
+					Config:      fmt.Sprintf(orgUsersResourceByUsername, orgId, "test-acc-unknown@acme.com", `"managers"`),
+					PlanOnly:    true,
+					ExpectError: regexp.MustCompile(`not found: test-acc-unknown@acme.com \(origin uaa\)`),
+				},
+				{
+					Config: fmt.Sprintf(orgUsersResourceByUsername, orgId, "test-acc-by-username@acme.com", `"managers", "auditors"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckOrgUsersExists(ref, &usersMap),
+						testAccCheckMapUserInside("test-acc-by-username@acme.com", "managers", &usersMap),
+						testAccCheckMapUserInside("test-acc-by-username@acme.com", "auditors", &usersMap),
+						resource.TestCheckResourceAttr(ref, "users_by_username.#", "1"),
+						resource.TestCheckResourceAttr(ref, "users_by_username.0.roles.#", "2"),
+					),
+				},
+				{
+					Config: fmt.Sprintf(orgUsersResourceByUsername, orgId, "test-acc-by-username@acme.com", `"auditors"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckOrgUsersExists(ref, &usersMap),
+						testAccCheckMapUserInside("test-acc-by-username@acme.com", "auditors", &usersMap),
+						resource.TestCheckResourceAttr(ref, "users_by_username.0.roles.#", "1"),
+					),
+				},
+			},
+		})
+}
+
 func testAccCheckOrgUsersExists(resource string, users *map[string][]ccv2.User) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		session := testAccProvider.Meta().(*managers.Session)
@@ -8,6 +8,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 
+	"code.cloudfoundry.org/cfnetworking-cli-api/cfnetworking/cfnetv1"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"code.cloudfoundry.org/cli/resources"
@@ -53,9 +54,20 @@ func ResourceRoute() *schema.Resource {
 				Optional: true,
 			},
 			"port": {
-				Type:     schema.TypeInt,
-				Optional: true,
-				Computed: true,
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ConflictsWith: []string{"random_port"},
+			},
+			"random_port": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				ConflictsWith: []string{"port"},
+				// a port is only picked when creating the route, an existing route keeps its port
+				// whatever random_port is, e.g. after upgrading a v0 state
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return d.Id() != ""
+				},
 			},
 			"path": {
 				Type:     schema.TypeString,
@@ -66,6 +78,32 @@ func ResourceRoute() *schema.Resource {
 				Type:     schema.TypeString,
 				Computed: true,
 			},
+			"internal_source_apps": {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      resourceStringHash,
+			},
+			"internal_source_policies": {
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"source_app": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"destination_app": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"port": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
 			"target": {
 				Type: schema.TypeSet,
 				Set: func(v interface{}) int {
@@ -168,17 +206,33 @@ func resourceRouteCreate(ctx context.Context, d *schema.ResourceData, meta inter
 		return diag.Errorf("client is nil")
 	}
 
+	sources := getRouteInternalSources(d)
+	if len(sources) > 0 {
+		if err := validateRouteInternalDomain(session, d.Get("domain").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	var route = resources.Route{}
+	randomPort := d.Get("random_port").(bool)
 
 	// Call create route API
 	operation := func() error {
 		var err error
+		port := d.Get("port").(int)
+		if randomPort {
+			// a new port is picked on each attempt as another route may have taken it in the meantime
+			port, err = randomFreePort(session, d.Get("domain").(string))
+			if err != nil {
+				return backoff.Permanent(err)
+			}
+		}
 		route, _, err = session.ClientV3.CreateRoute(resources.Route{
 			DomainGUID: d.Get("domain").(string),
 			SpaceGUID:  d.Get("space").(string),
 			Host:       d.Get("hostname").(string),
 			Path:       d.Get("path").(string),
-			Port:       d.Get("port").(int),
+			Port:       port,
 		})
 
 		if v, ok := d.GetOk("port"); ok {
@@ -188,6 +242,9 @@ func resourceRouteCreate(ctx context.Context, d *schema.ResourceData, meta inter
 			if unexpected, ok := err.(ccerror.V3UnexpectedResponseError); ok && unexpected.ResponseCode == http.StatusInternalServerError {
 				return err
 			}
+			if _, ok := err.(ccerror.UnprocessableEntityError); ok && randomPort {
+				return err
+			}
 			return backoff.Permanent(err)
 		}
 		return nil
@@ -225,10 +282,30 @@ func resourceRouteCreate(ctx context.Context, d *schema.ResourceData, meta inter
 		_ = d.Set("target", t)
 	}
 
+	if len(sources) > 0 {
+		owned, err := reconcileRouteNetworkPolicies(ctx, session, route.GUID, sources, []cfnetv1.Policy{})
+		_ = d.Set("internal_source_policies", flattenRoutePolicies(owned))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
 	d.SetId(route.GUID)
 	return diag.FromErr(err)
 }
 
+func getRouteInternalSources(d *schema.ResourceData) []string {
+	return getStringsFromSet(d.Get("internal_source_apps"))
+}
+
+func getStringsFromSet(v interface{}) []string {
+	values := make([]string, 0)
+	for _, value := range v.(*schema.Set).List() {
+		values = append(values, value.(string))
+	}
+	return values
+}
+
 func resourceRouteRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
@@ -256,6 +333,17 @@ func resourceRouteRead(ctx context.Context, d *schema.ResourceData, meta interfa
 		return diag.FromErr(err)
 	}
 
+	sources := getRouteInternalSources(d)
+	owned := getRouteOwnedPolicies(d)
+	if len(sources) > 0 || len(owned) > 0 {
+		sources, owned, err = readRouteNetworkPolicySources(ctx, session, route.GUID, sources, owned)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		_ = d.Set("internal_source_apps", sources)
+		_ = d.Set("internal_source_policies", flattenRoutePolicies(owned))
+	}
+
 	if _, ok := d.GetOk("target"); !ok && !IsImportState(d) {
 		return nil
 	}
@@ -300,6 +388,23 @@ func resourceRouteRead(ctx context.Context, d *schema.ResourceData, meta interfa
 func resourceRouteUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
+	diags := resourceRouteUpdateRoute(ctx, d, session)
+	if diags.HasError() || (!d.HasChange("internal_source_apps") && !d.HasChange("target")) {
+		return diags
+	}
+
+	sources := getRouteInternalSources(d)
+	if len(sources) > 0 {
+		if err := validateRouteInternalDomain(session, d.Get("domain").(string)); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	owned, err := reconcileRouteNetworkPolicies(ctx, session, d.Id(), sources, getRouteOwnedPolicies(d))
+	_ = d.Set("internal_source_policies", flattenRoutePolicies(owned))
+	return append(diags, diag.FromErr(err)...)
+}
+
+func resourceRouteUpdateRoute(ctx context.Context, d *schema.ResourceData, session *managers.Session) diag.Diagnostics {
 	if d.HasChange("domain") || d.HasChange("space") || d.HasChange("hostname") || d.HasChange("target") {
 		// Delete and recreate
 		var route = resources.Route{}
@@ -374,6 +479,14 @@ func resourceRouteUpdate(ctx context.Context, d *schema.ResourceData, meta inter
 func resourceRouteDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
+	if owned := getRouteOwnedPolicies(d); len(owned) > 0 {
+		owned, err := reconcileRouteNetworkPolicies(ctx, session, d.Id(), []string{}, owned)
+		if err != nil && !IsErrNotFound(err) {
+			_ = d.Set("internal_source_policies", flattenRoutePolicies(owned))
+			return diag.FromErr(err)
+		}
+	}
+
 	if targets, ok := d.GetOk("target"); ok {
 		err := removeRouteDestinationV3(d.Id(), GetListOfStructs(targets.(*schema.Set).List()), session)
 		if err != nil {
@@ -392,3 +505,206 @@ func resourceRouteDelete(ctx context.Context, d *schema.ResourceData, meta inter
 	})
 	return diag.FromErr(err)
 }
+
+// routeDestinationPort is an app and the port it listens on behind a route
+type routeDestinationPort struct {
+	app  string
+	port int
+}
+
+func getRouteDestinationPorts(ctx context.Context, session *managers.Session, routeGUID string) ([]routeDestinationPort, error) {
+	destinations, err := session.ClientGo.Routes.GetDestinations(ctx, routeGUID)
+	if err != nil {
+		return nil, err
+	}
+	dests := make([]routeDestinationPort, 0)
+	for _, dest := range destinations.Destinations {
+		if dest.App.GUID == nil {
+			continue
+		}
+		// when no port is given, app listens on default port
+		port := 8080
+		if dest.Port != nil && *dest.Port > 0 {
+			port = *dest.Port
+		}
+		dests = append(dests, routeDestinationPort{app: *dest.App.GUID, port: port})
+	}
+	return dests, nil
+}
+
+// routeNetworkPolicies builds container to container policies from each source app to every destination of a route
+func routeNetworkPolicies(sources []string, dests []routeDestinationPort) []cfnetv1.Policy {
+	policies := make([]cfnetv1.Policy, 0)
+	for _, source := range sources {
+		for _, dest := range dests {
+			policies = append(policies, cfnetv1.Policy{
+				Source: cfnetv1.PolicySource{
+					ID: source,
+				},
+				Destination: cfnetv1.PolicyDestination{
+					Protocol: cfnetv1.PolicyProtocolTCP,
+					ID:       dest.app,
+					Ports: cfnetv1.Ports{
+						Start: dest.port,
+						End:   dest.port,
+					},
+				},
+			})
+		}
+	}
+	return policies
+}
+
+// reconcileRouteNetworkPolicies creates policies from sources to route destinations which do not exist yet and
+// removes policies created by the route which are no more needed, e.g. when a source is removed or an app is
+// unmapped from the route. Only policies created by the route are removed, as they are the only ones known to
+// not be needed by anything else, it returns policies created by the route which still exist.
+func reconcileRouteNetworkPolicies(ctx context.Context, session *managers.Session, routeGUID string, sources []string, owned []cfnetv1.Policy) ([]cfnetv1.Policy, error) {
+	var dests []routeDestinationPort
+	if len(sources) > 0 {
+		var err error
+		dests, err = getRouteDestinationPorts(ctx, session, routeGUID)
+		if err != nil {
+			return owned, err
+		}
+	}
+	wanted := routeNetworkPolicies(sources, dests)
+
+	kept := make([]cfnetv1.Policy, 0)
+	remove := make([]cfnetv1.Policy, 0)
+	for _, policy := range owned {
+		if isInSlice(wanted, func(object interface{}) bool { return sameRoutePolicy(object.(cfnetv1.Policy), policy) }) {
+			kept = append(kept, policy)
+		} else {
+			remove = append(remove, policy)
+		}
+	}
+	if len(remove) > 0 {
+		if err := session.NetClient.RemovePolicies(remove); err != nil {
+			return owned, err
+		}
+	}
+	if len(wanted) == 0 {
+		return kept, nil
+	}
+
+	destApps := make([]string, 0)
+	for _, dest := range dests {
+		destApps = append(destApps, dest.app)
+	}
+	policies, err := session.NetClient.ListPolicies(destApps...)
+	if err != nil {
+		return kept, err
+	}
+	add := make([]cfnetv1.Policy, 0)
+	for _, policy := range wanted {
+		if !routePolicyAllowed(policies, policy.Source.ID, policy.Destination.ID, policy.Destination.Ports.Start) {
+			add = append(add, policy)
+		}
+	}
+	if len(add) == 0 {
+		return kept, nil
+	}
+	if err := session.NetClient.CreatePolicies(add); err != nil {
+		return kept, err
+	}
+	return append(kept, add...), nil
+}
+
+// readRouteNetworkPolicySources returns sources which are allowed to reach every destination of the route
+// and policies created by the route which still exist
+func readRouteNetworkPolicySources(ctx context.Context, session *managers.Session, routeGUID string, sources []string, owned []cfnetv1.Policy) ([]string, []cfnetv1.Policy, error) {
+	dests, err := getRouteDestinationPorts(ctx, session, routeGUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	apps := make([]string, 0)
+	for _, dest := range dests {
+		apps = append(apps, dest.app)
+	}
+	for _, policy := range owned {
+		apps = append(apps, policy.Destination.ID)
+	}
+	if len(apps) == 0 {
+		return sources, owned, nil
+	}
+	policies, err := session.NetClient.ListPolicies(apps...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	final := make([]string, 0)
+	for _, source := range sources {
+		allowed := true
+		for _, dest := range dests {
+			if !routePolicyAllowed(policies, source, dest.app, dest.port) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			final = append(final, source)
+		}
+	}
+	existing := make([]cfnetv1.Policy, 0)
+	for _, policy := range owned {
+		if isInSlice(policies, func(object interface{}) bool { return sameRoutePolicy(object.(cfnetv1.Policy), policy) }) {
+			existing = append(existing, policy)
+		}
+	}
+	return final, existing, nil
+}
+
+// routePolicyAllowed tells if one of the policies allows source to reach port of destination
+func routePolicyAllowed(policies []cfnetv1.Policy, source string, destination string, port int) bool {
+	return isInSlice(policies, func(object interface{}) bool {
+		policy := object.(cfnetv1.Policy)
+		return policy.Source.ID == source &&
+			policy.Destination.ID == destination &&
+			policy.Destination.Protocol == cfnetv1.PolicyProtocolTCP &&
+			policy.Destination.Ports.Start <= port &&
+			policy.Destination.Ports.End >= port
+	})
+}
+
+func sameRoutePolicy(a cfnetv1.Policy, b cfnetv1.Policy) bool {
+	return a.Source.ID == b.Source.ID &&
+		a.Destination.ID == b.Destination.ID &&
+		a.Destination.Protocol == b.Destination.Protocol &&
+		a.Destination.Ports == b.Destination.Ports
+}
+
+// getRouteOwnedPolicies gives policies created by the route from internal_source_policies
+func getRouteOwnedPolicies(d *schema.ResourceData) []cfnetv1.Policy {
+	policies := make([]cfnetv1.Policy, 0)
+	for _, p := range GetListOfStructs(d.Get("internal_source_policies")) {
+		policies = append(policies, routeNetworkPolicies(
+			[]string{p["source_app"].(string)},
+			[]routeDestinationPort{{app: p["destination_app"].(string), port: p["port"].(int)}},
+		)...)
+	}
+	return policies
+}
+
+func flattenRoutePolicies(policies []cfnetv1.Policy) []map[string]interface{} {
+	flat := make([]map[string]interface{}, 0)
+	for _, policy := range policies {
+		flat = append(flat, map[string]interface{}{
+			"source_app":      policy.Source.ID,
+			"destination_app": policy.Destination.ID,
+			"port":            policy.Destination.Ports.Start,
+		})
+	}
+	return flat
+}
+
+func validateRouteInternalDomain(session *managers.Session, domainGUID string) error {
+	domain, _, err := session.ClientV3.GetDomain(domainGUID)
+	if err != nil {
+		return err
+	}
+	if !domain.Internal.IsSet || !domain.Internal.Value {
+		return fmt.Errorf("internal_source_apps can only be set on a route using an internal domain, '%s' is not internal", domain.Name)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package cloudfoundry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestRouteMigrateStateV0toV1(t *testing.T) {
+	rawState := map[string]interface{}{
+		"id":          "a_route",
+		"domain":      "domain-guid",
+		"space":       "space-guid",
+		"port":        1024,
+		"random_port": true,
+	}
+	actual, err := patchRouteV0(context.Background(), rawState, nil)
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if actual["port"] != 1024 || actual["random_port"] != true {
+		t.Fatalf("bad: upgraded state %#v", actual)
+	}
+}
+
+func TestRouteRandomPortNoReplacement(t *testing.T) {
+	cases := map[string]struct {
+		Attributes map[string]string
+		Config     map[string]interface{}
+	}{
+		"v0_random_port_removed_from_config": {
+			Attributes: map[string]string{
+				"domain":      "domain-guid",
+				"space":       "space-guid",
+				"port":        "1024",
+				"random_port": "true",
+			},
+			Config: map[string]interface{}{
+				"domain": "domain-guid",
+				"space":  "space-guid",
+			},
+		},
+		"v0_random_port_kept_in_config": {
+			Attributes: map[string]string{
+				"domain":      "domain-guid",
+				"space":       "space-guid",
+				"port":        "1024",
+				"random_port": "true",
+			},
+			Config: map[string]interface{}{
+				"domain":      "domain-guid",
+				"space":       "space-guid",
+				"random_port": true,
+			},
+		},
+		"random_port_added_to_config": {
+			Attributes: map[string]string{
+				"domain": "domain-guid",
+				"space":  "space-guid",
+				"port":   "1024",
+			},
+			Config: map[string]interface{}{
+				"domain":      "domain-guid",
+				"space":       "space-guid",
+				"random_port": true,
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		is := &terraform.InstanceState{
+			ID:         "a_route",
+			Attributes: tc.Attributes,
+		}
+		diff, err := ResourceRoute().Diff(context.Background(), is, terraform.NewResourceConfigRaw(tc.Config), nil)
+		if err != nil {
+			t.Fatalf("bad: %s, err: %#v", tn, err)
+		}
+		if diff != nil && diff.RequiresNew() {
+			t.Fatalf("bad: %s, route would be replaced: %#v", tn, diff)
+		}
+		if diff != nil && diff.Attributes["random_port"] != nil {
+			t.Fatalf("bad: %s, unexpected random_port diff: %#v", tn, diff.Attributes["random_port"])
+		}
+	}
+}
@@ -98,6 +98,70 @@ resource "cloudfoundry_route" "test-app-route" {
 }
 `
 
+const routeResourceRandomPort = `
+
+data "cloudfoundry_router_group" "tcp" {
+  name = "default-tcp"
+}
+data "cloudfoundry_org" "org" {
+    name = "%s"
+}
+data "cloudfoundry_space" "space" {
+    name = "%s"
+	org = "${data.cloudfoundry_org.org.id}"
+}
+
+resource "cloudfoundry_domain" "tcp" {
+  sub_domain = "tcp-route-res"
+  domain = "%s"
+  router_group = "${data.cloudfoundry_router_group.tcp.id}"
+}
+resource "cloudfoundry_route" "test-tcp-route" {
+	domain = "${cloudfoundry_domain.tcp.id}"
+	space = "${data.cloudfoundry_space.space.id}"
+	random_port = true
+}
+`
+
+const routeResourceInternalSources = `
+
+data "cloudfoundry_domain" "internal" {
+    name = "apps.internal"
+}
+data "cloudfoundry_org" "org" {
+    name = "%s"
+}
+data "cloudfoundry_space" "space" {
+    name = "%s"
+	org = "${data.cloudfoundry_org.org.id}"
+}
+
+resource "cloudfoundry_app" "test-app-front" {
+	name = "test-app-front"
+	space = "${data.cloudfoundry_space.space.id}"
+	timeout = 1800
+	buildpack = "binary_buildpack"
+	path = "%s"
+}
+resource "cloudfoundry_app" "test-app-back" {
+	name = "test-app-back"
+	space = "${data.cloudfoundry_space.space.id}"
+	timeout = 1800
+	buildpack = "binary_buildpack"
+	path = "%s"
+}
+resource "cloudfoundry_route" "test-app-internal" {
+	domain = "${data.cloudfoundry_domain.internal.id}"
+	space = "${data.cloudfoundry_space.space.id}"
+	hostname = "test-app-internal"
+	internal_source_apps = [ "${cloudfoundry_app.test-app-front.id}" ]
+
+	target {
+		app = "${cloudfoundry_app.test-app-back.id}"
+	}
+}
+`
+
 func TestAccResRoute_normal(t *testing.T) {
 
 	_, orgName := defaultTestOrg(t)
@@ -156,6 +220,94 @@ func TestAccResRoute_normal(t *testing.T) {
 		})
 }
 
+func TestAccResRoute_randomPort(t *testing.T) {
+
+	_, orgName := defaultTestOrg(t)
+	_, spaceName := defaultTestSpace(t)
+
+	refRoute := "cloudfoundry_route.test-tcp-route"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(routeResourceRandomPort,
+						orgName, spaceName,
+						defaultAppDomain()),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckRouteExists(refRoute, func() error { return nil }),
+						resource.TestCheckResourceAttr(
+							refRoute, "random_port", "true"),
+						resource.TestCheckResourceAttrSet(
+							refRoute, "port"),
+					),
+				},
+			},
+		})
+}
+
+func TestAccResRoute_internalSources(t *testing.T) {
+
+	_, orgName := defaultTestOrg(t)
+	_, spaceName := defaultTestSpace(t)
+
+	refRoute := "cloudfoundry_route.test-app-internal"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckRouteDestroyed([]string{"test-app-internal"}, "apps.internal"),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(routeResourceInternalSources,
+						orgName, spaceName,
+						appPath, appPath),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckRouteExists(refRoute, func() error { return nil }),
+						testAccCheckRouteInternalPolicies(refRoute, "cloudfoundry_app.test-app-front", "cloudfoundry_app.test-app-back"),
+						resource.TestCheckResourceAttr(
+							refRoute, "internal_source_apps.#", "1"),
+						resource.TestCheckResourceAttr(
+							refRoute, "internal_source_policies.#", "1"),
+					),
+				},
+			},
+		})
+}
+
+func testAccCheckRouteInternalPolicies(resRoute, resSource, resDestination string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		session := testAccProvider.Meta().(*managers.Session)
+
+		source, ok := s.RootModule().Resources[resSource]
+		if !ok {
+			return fmt.Errorf("app '%s' not found in terraform state", resSource)
+		}
+		destination, ok := s.RootModule().Resources[resDestination]
+		if !ok {
+			return fmt.Errorf("app '%s' not found in terraform state", resDestination)
+		}
+
+		policies, err := session.NetClient.ListPolicies(destination.Primary.ID)
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if policy.Source.ID == source.Primary.ID && policy.Destination.ID == destination.Primary.ID {
+				return nil
+			}
+		}
+		return fmt.Errorf("no network policy found from '%s' to '%s' for route '%s'", resSource, resDestination, resRoute)
+	}
+}
+
 func testAccCheckRouteExists(resRoute string, validate func() error) resource.TestCheckFunc {
 
 	return func(s *terraform.State) error {
@@ -12,9 +12,6 @@ func patchRouteV0(ctx context.Context, rawState map[string]interface{}, meta int
 	if rawState == nil {
 		rawState = map[string]interface{}{}
 	}
-	// random_port is no more
-	delete(rawState, "random_port")
-
 	return rawState, nil
 }
 
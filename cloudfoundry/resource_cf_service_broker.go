@@ -4,12 +4,18 @@ import (
 	"context"
 	"crypto/sha1"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -22,6 +28,18 @@ const (
 	catalogEndpoint = "/v2/catalog"
 )
 
+// brokerCatalog is the subset of a service broker catalog (as defined by OSBAPI) describing offerings and plans
+type brokerCatalog struct {
+	Services []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Plans []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"plans"`
+	} `json:"services"`
+}
+
 func resourceServiceBroker() *schema.Resource {
 
 	return &schema.Resource{
@@ -35,6 +53,14 @@ func resourceServiceBroker() *schema.Resource {
 			StateContext: ImportReadContext(resourceServiceBrokerRead),
 		},
 
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
+			Delete: schema.DefaultTimeout(5 * time.Minute),
+		},
+
+		CustomizeDiff: resourceServiceBrokerCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 
 			"name": {
@@ -67,6 +93,28 @@ func resourceServiceBroker() *schema.Resource {
 				Type:     schema.TypeMap,
 				Computed: true,
 			},
+			"catalog": {
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Service offerings and plans of the broker catalog, it shows offerings and plans added or removed by the broker in plan",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"service_offering": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"service_offering_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"plans": {
+							Type:     schema.TypeMap,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
 			"catalog_hash": {
 				Type:     schema.TypeString,
 				Computed: true,
@@ -76,6 +124,7 @@ func resourceServiceBroker() *schema.Resource {
 				Type:        schema.TypeBool,
 				Optional:    true,
 				Default:     false,
+				Deprecated:  "Catalog changes are now detected during plan and shown in catalog attribute",
 				Description: "Special marker to know and trigger a service broker update, this should not be set to true on your resource declaration",
 			},
 			"fail_when_catalog_not_accessible": {
@@ -90,7 +139,7 @@ func resourceServiceBroker() *schema.Resource {
 	}
 }
 
-func resourceServiceBrokerCreate(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+func resourceServiceBrokerCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
 	// do as first to not try add broker if catalog not accessible
@@ -99,21 +148,40 @@ func resourceServiceBrokerCreate(_ context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(err)
 	}
 
-	sb, _, err := session.ClientV2.CreateServiceBroker(
-		d.Get("name").(string),
+	name := d.Get("name").(string)
+	sbCreate := resource.NewServiceBrokerCreate(
+		name,
+		d.Get("url").(string),
 		d.Get("username").(string),
 		d.Get("password").(string),
-		d.Get("url").(string),
-		d.Get("space").(string),
 	)
+	if space, ok := d.GetOk("space"); ok {
+		sbCreate.WithSpace(space.(string))
+	}
+	jobGUID, err := session.ClientGo.ServiceBrokers.Create(ctx, sbCreate)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	if err = readServiceDetail(sb.GUID, session, d); err != nil {
+
+	// broker is registered before its catalog is synchronized, id is set before waiting
+	// to let terraform taint the broker if synchronization fails
+	opts := client.NewServiceBrokerListOptions()
+	opts.Names = client.Filter{Values: []string{name}}
+	sb, err := session.ClientGo.ServiceBrokers.Single(ctx, opts)
+	if err != nil {
 		return diag.FromErr(err)
 	}
 	d.SetId(sb.GUID)
 
+	err = pollServiceBrokerJob(ctx, session, jobGUID, d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	if err = readServiceDetail(ctx, sb.GUID, session, d); err != nil {
+		return diag.FromErr(err)
+	}
+
 	err = metadataCreate(serviceBrokerMetadata, d, meta)
 	if err != nil {
 		return diag.FromErr(err)
@@ -122,16 +190,18 @@ func resourceServiceBrokerCreate(_ context.Context, d *schema.ResourceData, meta
 	return nil
 }
 
-func resourceServiceBrokerRead(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+func resourceServiceBrokerRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
-	// do as first to not try add broker if catalog not accessible
-	err := serviceBrokerUpdateCatalogSignature(d, meta)
-	if err != nil {
-		return diag.FromErr(err)
+	// catalog changes are detected during plan, hash is only computed when missing (e.g. on import)
+	if d.Get("catalog_hash").(string) == "" {
+		err := serviceBrokerUpdateCatalogSignature(d, meta)
+		if err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
-	sb, _, err := session.ClientV2.GetServiceBroker(d.Id())
+	sb, err := session.ClientGo.ServiceBrokers.Get(ctx, d.Id())
 	if err != nil {
 		if IsErrNotFound(err) {
 			d.SetId("")
@@ -139,15 +209,19 @@ func resourceServiceBrokerRead(_ context.Context, d *schema.ResourceData, meta i
 		}
 		return diag.FromErr(err)
 	}
-	err = readServiceDetail(d.Id(), session, d)
+	err = readServiceDetail(ctx, d.Id(), session, d)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
 	_ = d.Set("name", sb.Name)
-	_ = d.Set("url", sb.BrokerURL)
-	_ = d.Set("username", sb.AuthUsername)
-	_ = d.Set("space", sb.SpaceGUID)
+	_ = d.Set("url", sb.URL)
+	// credentials are never returned by v3 api, username is kept from state
+	space := ""
+	if sb.Relationships.Space.Data != nil {
+		space = sb.Relationships.Space.Data.GUID
+	}
+	_ = d.Set("space", space)
 
 	err = metadataRead(serviceBrokerMetadata, d, meta, false)
 	if err != nil {
@@ -156,7 +230,7 @@ func resourceServiceBrokerRead(_ context.Context, d *schema.ResourceData, meta i
 	return nil
 }
 
-func resourceServiceBrokerUpdate(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+func resourceServiceBrokerUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
 	// do as first to not try add broker if catalog not accessible
@@ -165,21 +239,32 @@ func resourceServiceBrokerUpdate(_ context.Context, d *schema.ResourceData, meta
 		return diag.FromErr(err)
 	}
 
-	_, _, err = session.ClientV2.UpdateServiceBroker(ccv2.ServiceBroker{
-		GUID:         d.Id(),
-		AuthUsername: d.Get("username").(string),
-		AuthPassword: d.Get("password").(string),
-		BrokerURL:    d.Get("url").(string),
-		SpaceGUID:    d.Get("space").(string),
-		Name:         d.Get("name").(string),
-	})
-	if err != nil {
-		return diag.FromErr(err)
+	if d.HasChanges("name", "url", "username", "password", "catalog_hash", "catalog", "catalog_change") {
+		// an update without any attribute still makes cloud controller synchronize broker catalog
+		sbUpdate := resource.NewServiceBrokerUpdate()
+		if d.HasChange("name") {
+			sbUpdate.WithName(d.Get("name").(string))
+		}
+		if d.HasChange("url") {
+			sbUpdate.WithURL(d.Get("url").(string))
+		}
+		if d.HasChanges("username", "password") {
+			sbUpdate.WithCredentials(d.Get("username").(string), d.Get("password").(string))
+		}
+		jobGUID, _, err := session.ClientGo.ServiceBrokers.Update(ctx, d.Id(), sbUpdate)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		err = pollServiceBrokerJob(ctx, session, jobGUID, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return diag.FromErr(err)
+		}
 	}
 
-	if err = readServiceDetail(d.Id(), session, d); err != nil {
+	if err = readServiceDetail(ctx, d.Id(), session, d); err != nil {
 		return diag.FromErr(err)
 	}
+	_ = d.Set("catalog_change", false)
 
 	err = metadataUpdate(serviceBrokerMetadata, d, meta)
 	if err != nil {
@@ -188,61 +273,144 @@ func resourceServiceBrokerUpdate(_ context.Context, d *schema.ResourceData, meta
 	return nil
 }
 
-func resourceServiceBrokerDelete(_ context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+func resourceServiceBrokerDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
-	if !session.PurgeWhenDelete {
-		_, err := session.ClientV2.DeleteServiceBroker(d.Id())
-		return diag.FromErr(err)
-	}
-
-	svcs, _, err := session.ClientV2.GetServices(ccv2.FilterEqual(constant.ServiceBrokerGUIDFilter, d.Id()))
-	if err != nil {
-		return diag.FromErr(err)
-	}
-	for _, svc := range svcs {
-		sis, _, err := session.ClientV2.GetServiceInstances(ccv2.FilterEqual(constant.ServiceGUIDFilter, svc.GUID))
+	if session.PurgeWhenDelete {
+		svcs, _, err := session.ClientV2.GetServices(ccv2.FilterEqual(constant.ServiceBrokerGUIDFilter, d.Id()))
 		if err != nil {
 			return diag.FromErr(err)
 		}
-		for _, si := range sis {
-			_, _, err := session.ClientV2.DeleteServiceInstance(si.GUID, true, true)
+		for _, svc := range svcs {
+			sis, _, err := session.ClientV2.GetServiceInstances(ccv2.FilterEqual(constant.ServiceGUIDFilter, svc.GUID))
 			if err != nil {
 				return diag.FromErr(err)
 			}
+			for _, si := range sis {
+				_, _, err := session.ClientV2.DeleteServiceInstance(si.GUID, true, true)
+				if err != nil {
+					return diag.FromErr(err)
+				}
+			}
+		}
+	}
+
+	jobGUID, err := session.ClientGo.ServiceBrokers.Delete(ctx, d.Id())
+	if err != nil {
+		if IsErrNotFound(err) {
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	return diag.FromErr(pollServiceBrokerJob(ctx, session, jobGUID, d.Timeout(schema.TimeoutDelete)))
+}
+
+// resourceServiceBrokerCustomizeDiff compares catalog served by the broker with the one registered in cloud controller
+// and plans the catalog synchronization when they differ
+func resourceServiceBrokerCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	session := meta.(*managers.Session)
+
+	url := d.Get("url").(string)
+	username := d.Get("username").(string)
+	password := d.Get("password").(string)
+	if url == "" || username == "" || password == "" {
+		// values not known yet
+		return nil
+	}
+	body, err := serviceBrokerFetchCatalog(session, url, username, password)
+	if err != nil {
+		log.Printf("[WARN] skipping catalog diff, broker catalog is not accessible: %s", err.Error())
+		return nil
+	}
+	signature, err := serviceBrokerCatalogSignature(body)
+	if err != nil {
+		return err
+	}
+	catalog, err := serviceBrokerCatalogFromBody(body)
+	if err != nil {
+		log.Printf("[WARN] skipping catalog diff, broker catalog can't be decoded: %s", err.Error())
+		return nil
+	}
+
+	oldCatalog, _ := d.GetChange("catalog")
+	if !reflect.DeepEqual(normalizeServiceBrokerCatalog(oldCatalog.([]interface{})), catalog) {
+		if err := d.SetNew("catalog", catalog); err != nil {
+			return err
+		}
+		if err := d.SetNewComputed("services"); err != nil {
+			return err
+		}
+		if err := d.SetNewComputed("service_plans"); err != nil {
+			return err
 		}
 	}
-	_, err = session.ClientV2.DeleteServiceBroker(d.Id())
-	return diag.FromErr(err)
+	oldSignature, _ := d.GetChange("catalog_hash")
+	if oldSignature.(string) != signature {
+		return d.SetNew("catalog_hash", signature)
+	}
+	return nil
+}
+
+// pollServiceBrokerJob waits for an asynchronous service broker job, job guid is empty when request was synchronous
+func pollServiceBrokerJob(ctx context.Context, session *managers.Session, jobGUID string, timeout time.Duration) error {
+	if jobGUID == "" {
+		return nil
+	}
+	opts := client.NewPollingOptions()
+	opts.Timeout = timeout
+	return session.ClientGo.Jobs.PollComplete(ctx, jobGUID, opts)
 }
 
-func readServiceDetail(id string, session *managers.Session, d *schema.ResourceData) error {
-	services, _, err := session.ClientV2.GetServices(ccv2.FilterEqual(constant.ServiceBrokerGUIDFilter, id))
+func readServiceDetail(ctx context.Context, id string, session *managers.Session, d *schema.ResourceData) error {
+	offeringOpts := client.NewServiceOfferingListOptions()
+	offeringOpts.ServiceBrokerGUIDs = client.Filter{Values: []string{id}}
+	offerings, err := session.ClientGo.ServiceOfferings.ListAll(ctx, offeringOpts)
+	if err != nil {
+		return err
+	}
+	planOpts := client.NewServicePlanListOptions()
+	planOpts.ServiceBrokerGUIDs = client.Filter{Values: []string{id}}
+	plans, err := session.ClientGo.ServicePlans.ListAll(ctx, planOpts)
 	if err != nil {
 		return err
 	}
 
 	servicePlansTf := make(map[string]interface{})
 	servicesTf := make(map[string]interface{})
-	for _, s := range services {
-		servicesTf[s.Label] = s.GUID
-		servicePlans, _, err := session.ClientV2.GetServicePlans(ccv2.FilterEqual(constant.ServiceGUIDFilter, s.GUID))
-		if err != nil {
-			return err
-		}
-		for _, sp := range servicePlans {
-			servicePlansTf[s.Label+"/"+sp.Name] = sp.GUID
+	catalog := make([]interface{}, 0, len(offerings))
+	for _, s := range offerings {
+		servicesTf[s.Name] = s.GUID
+		catalogPlans := make(map[string]interface{})
+		for _, sp := range plans {
+			if sp.Relationships.ServiceOffering.Data == nil || sp.Relationships.ServiceOffering.Data.GUID != s.GUID {
+				continue
+			}
+			servicePlansTf[s.Name+"/"+sp.Name] = sp.GUID
+			catalogPlans[sp.Name] = sp.BrokerCatalog.ID
 		}
+		catalog = append(catalog, map[string]interface{}{
+			"service_offering":    s.Name,
+			"service_offering_id": s.BrokerCatalog.ID,
+			"plans":               catalogPlans,
+		})
 	}
 	_ = d.Set("service_plans", servicePlansTf)
 	_ = d.Set("services", servicesTf)
+	_ = d.Set("catalog", sortServiceBrokerCatalog(catalog))
 
-	return err
+	return nil
 }
 
 func serviceBrokerUpdateCatalogSignature(d *schema.ResourceData, meta interface{}) error {
 	session := meta.(*managers.Session)
 
-	signature, err := serviceBrokerCatalogSignature(d, meta)
+	body, err := serviceBrokerFetchCatalog(session, d.Get("url").(string), d.Get("username").(string), d.Get("password").(string))
+	signature := ""
+	if err == nil {
+		signature, err = serviceBrokerCatalogSignature(body)
+	}
 	failNotAccessible := d.Get("fail_when_catalog_not_accessible").(bool)
 	if d.HasChange("fail_when_catalog_not_accessible") {
 		_, newFailNotAccessible := d.GetChange("fail_when_catalog_not_accessible")
@@ -263,29 +431,23 @@ func serviceBrokerUpdateCatalogSignature(d *schema.ResourceData, meta interface{
 		)
 		return nil
 	}
-	previousSignature := d.Get("catalog_hash")
 	_ = d.Set("catalog_hash", signature)
-	if d.IsNewResource() {
-		return nil
-	}
-	_ = d.Set("catalog_change", previousSignature != signature)
 	return nil
 }
 
-func serviceBrokerCatalogSignature(d *schema.ResourceData, meta interface{}) (string, error) {
-	client := meta.(*managers.Session).HttpClient
-	catalogUrl := d.Get("url").(string)
+// serviceBrokerFetchCatalog retrieves catalog directly from the broker
+func serviceBrokerFetchCatalog(session *managers.Session, catalogUrl, username, password string) ([]byte, error) {
 	catalogUrl = strings.TrimSuffix(catalogUrl, "/")
 	catalogUrl += catalogEndpoint
 	req, err := http.NewRequest("GET", catalogUrl, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Add("X-Broker-API-Version", "2.11")
-	req.SetBasicAuth(d.Get("username").(string), d.Get("password").(string))
-	resp, err := client.Do(req)
+	req.SetBasicAuth(username, password)
+	resp, err := session.HttpClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -293,16 +455,66 @@ func serviceBrokerCatalogSignature(d *schema.ResourceData, meta interface{}) (st
 
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("Status code: %s, Body: %s ", resp.Status, string(bodyBytes))
+		return nil, fmt.Errorf("Status code: %s, Body: %s ", resp.Status, string(bodyBytes))
 	}
+	return bodyBytes, nil
+}
 
+func serviceBrokerCatalogSignature(body []byte) (string, error) {
 	h := sha1.New()
-	_, err = h.Write(bodyBytes)
+	_, err := h.Write(body)
 	if err != nil {
 		return "", err
 	}
 	return base64.URLEncoding.EncodeToString(h.Sum(nil)), nil
 }
+
+// serviceBrokerCatalogFromBody converts a broker catalog to catalog attribute
+func serviceBrokerCatalogFromBody(body []byte) ([]interface{}, error) {
+	var bc brokerCatalog
+	if err := json.Unmarshal(body, &bc); err != nil {
+		return nil, err
+	}
+	catalog := make([]interface{}, 0, len(bc.Services))
+	for _, s := range bc.Services {
+		plans := make(map[string]interface{})
+		for _, p := range s.Plans {
+			plans[p.Name] = p.ID
+		}
+		catalog = append(catalog, map[string]interface{}{
+			"service_offering":    s.Name,
+			"service_offering_id": s.ID,
+			"plans":               plans,
+		})
+	}
+	return sortServiceBrokerCatalog(catalog), nil
+}
+
+// normalizeServiceBrokerCatalog makes a catalog read from state comparable with one built from broker
+func normalizeServiceBrokerCatalog(catalog []interface{}) []interface{} {
+	normalized := make([]interface{}, 0, len(catalog))
+	for _, c := range catalog {
+		offering := c.(map[string]interface{})
+		plans := make(map[string]interface{})
+		if p, ok := offering["plans"].(map[string]interface{}); ok {
+			plans = p
+		}
+		normalized = append(normalized, map[string]interface{}{
+			"service_offering":    offering["service_offering"],
+			"service_offering_id": offering["service_offering_id"],
+			"plans":               plans,
+		})
+	}
+	return sortServiceBrokerCatalog(normalized)
+}
+
+func sortServiceBrokerCatalog(catalog []interface{}) []interface{} {
+	sort.SliceStable(catalog, func(i, j int) bool {
+		return catalog[i].(map[string]interface{})["service_offering"].(string) <
+			catalog[j].(map[string]interface{})["service_offering"].(string)
+	})
+	return catalog
+}
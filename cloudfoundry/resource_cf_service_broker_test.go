@@ -94,8 +94,17 @@ func TestAccResServiceBroker_normal(t *testing.T) {
 							ref, "username", serviceBrokerUser),
 						resource.TestCheckResourceAttrSet(
 							ref, "service_plans."+serviceBrokerPlanPath),
+						resource.TestCheckResourceAttrSet(
+							ref, "catalog.0.service_offering"),
+						resource.TestCheckResourceAttrSet(
+							ref, "catalog.0.service_offering_id"),
 					),
 				},
+				resource.TestStep{
+					Config: fmt.Sprintf(sbResource,
+						serviceBrokerURL, serviceBrokerUser, serviceBrokerPassword),
+					PlanOnly: true,
+				},
 				resource.TestStep{
 					Config: fmt.Sprintf(sbResourceUpdateCatalog,
 						serviceBrokerURL, serviceBrokerUser, serviceBrokerPassword),
@@ -5,15 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
-	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3/constant"
 	"code.cloudfoundry.org/cli/resources"
 	"code.cloudfoundry.org/cli/types"
+	"github.com/blang/semver"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/structure"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
@@ -59,10 +61,11 @@ func resourceServiceInstance() *schema.Resource {
 				ForceNew: true,
 			},
 			"json_params": &schema.Schema{
-				Type:         schema.TypeString,
-				Optional:     true,
-				Default:      "",
-				ValidateFunc: validation.StringIsJSON,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Default:          "",
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: structure.SuppressJsonDiff,
 			},
 			"replace_on_service_plan_change": {
 				Type:     schema.TypeBool,
@@ -88,10 +91,102 @@ func resourceServiceInstance() *schema.Resource {
 					return true
 				},
 			},
+			"current_maintenance_info_version": &schema.Schema{
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Version of maintenance_info the service instance is at",
+			},
+			"available_maintenance_info_version": &schema.Schema{
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Version of maintenance_info published by the broker on the service plan",
+			},
+			"upgrade_available": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"auto_upgrade": &schema.Schema{
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				Description:   "Upgrade the service instance as soon as a new maintenance_info version is available on the service plan",
+				ConflictsWith: []string{"maintenance_info_version"},
+			},
+			"maintenance_info_version": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				Description:   "Version of maintenance_info to upgrade the service instance to",
+				ConflictsWith: []string{"auto_upgrade"},
+				// nothing to do when the instance is already at the pinned version, even if plan has a newer one
+				DiffSuppressFunc: func(k, oldValue, newValue string, d *schema.ResourceData) bool {
+					return d.Id() != "" && newValue != "" && newValue == d.Get("current_maintenance_info_version").(string)
+				},
+			},
+			"last_operation": &schema.Schema{
+				Type:        schema.TypeList,
+				Computed:    true,
+				Description: "Last operation on the service instance, an operation still in progress is resumed on next apply",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"state": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"description": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
 			labelsKey:      labelsSchema(),
 			annotationsKey: annotationsSchema(),
 		},
 		CustomizeDiff: customdiff.All(
+			func(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+				// an operation interrupted on terraform side must be resumed by an update
+				if isServiceInstanceLastOperationPending(d.Get("last_operation")) {
+					return d.SetNewComputed("last_operation")
+				}
+				return nil
+			},
+			func(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+				if d.Id() == "" || d.HasChange("service_plan") {
+					return nil
+				}
+				current := d.Get("current_maintenance_info_version").(string)
+				if pinned := d.Get("maintenance_info_version").(string); isMaintenanceInfoVersionOlder(pinned, current) {
+					return fmt.Errorf("maintenance_info_version %s is older than version %s the service instance is at, service instances can't be downgraded", pinned, current)
+				}
+				// upgrade is planned as a change of current version
+				if version := serviceInstanceUpgradeVersion(d, d.Get("current_maintenance_info_version").(string)); version != "" {
+					return d.SetNew("current_maintenance_info_version", version)
+				}
+				return nil
+			},
+			func(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+				// json_params are only sent to the broker on creation or when they change
+				if d.Id() != "" && !d.HasChange("json_params") {
+					return nil
+				}
+				if !d.NewValueKnown("json_params") || !d.NewValueKnown("service_plan") {
+					return nil
+				}
+				params, ok, err := serviceParamsFromConfig(nil, d.Get("json_params").(string))
+				if err != nil || !ok {
+					// invalid json is already reported by attribute validation
+					return nil
+				}
+				schemaType := servicePlanSchemaInstanceCreate
+				if d.Id() != "" {
+					schemaType = servicePlanSchemaInstanceUpdate
+				}
+				return validateServiceParams(ctx, meta.(*managers.Session), d.Get("service_plan").(string), schemaType, "json_params", params)
+			},
 			customdiff.ForceNewIf(
 				"service_plan", func(_ context.Context, d *schema.ResourceDiff, meta interface{}) bool {
 					if ok := d.Get("replace_on_service_plan_change").(bool); ok {
@@ -165,45 +260,30 @@ func resourceServiceInstanceCreate(ctx context.Context, d *schema.ResourceData,
 	serviceInstance.Parameters = paramsFormatted
 	serviceInstance.Metadata = &metadata
 
-	jobURL, _, err := session.ClientV3.CreateServiceInstance(serviceInstance)
+	_, _, err := session.ClientV3.CreateServiceInstance(serviceInstance)
 	if err != nil {
 		return diag.FromErr(err)
 	}
 
-	// Poll the state of the async job
-	err = common.PollingWithTimeout(func() (bool, error) {
-		job, _, err := session.ClientV3.GetJob(jobURL)
-		if err != nil {
-			return true, err
-		}
-
-		// Stop polling and return error if job failed
-		if job.State == constant.JobFailed {
-			return true, fmt.Errorf(
-				"Service Instance %s failed %s, reason: %+v",
-				name,
-				space,
-				job.Errors(),
-			)
-		}
-		// If job completed, check if the service instance is created
-		if job.State == constant.JobComplete {
-			si, _, _, err := session.ClientV3.GetServiceInstanceByNameAndSpace(name, space)
-			if err != nil {
-				return true, err
-			}
-			d.SetId(si.GUID)
-			return true, nil
-		}
-		// Last operation initial or inprogress or job not completed, continue polling
-		return false, nil
-	}, 5*time.Second, d.Timeout(schema.TimeoutCreate))
-
+	// Instance is registered in cloud controller before the broker finishes, id is set right now
+	// to keep track of the instance if apply is interrupted
+	si, found, err := getServiceInstanceByNameAndSpace(session, name, space)
 	if err != nil {
 		return diag.FromErr(err)
 	}
+	if !found {
+		return diag.Errorf("Service instance %s not found in space %s after creation", name, space)
+	}
+	d.SetId(si.GUID)
 
-	return nil
+	diags := waitServiceInstanceOperationDiags(ctx, d, session, d.Timeout(schema.TimeoutCreate))
+	// a creation still in progress must not taint the instance, waiting is resumed by an update on next apply
+	if diags.HasError() && isServiceInstanceLastOperationPending(d.Get("last_operation")) {
+		for i := range diags {
+			diags[i].Severity = diag.Warning
+		}
+	}
+	return diags
 }
 
 func resourceServiceInstanceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -237,6 +317,20 @@ func resourceServiceInstanceRead(ctx context.Context, d *schema.ResourceData, me
 	d.Set("name", serviceInstance.Name)
 	d.Set("service_plan", serviceInstance.ServicePlanGUID)
 	d.Set("space", serviceInstance.SpaceGUID)
+	setServiceInstanceLastOperation(d, serviceInstance.LastOperation)
+	d.Set("current_maintenance_info_version", serviceInstance.MaintenanceInfoVersion)
+	d.Set("upgrade_available", serviceInstance.UpgradeAvailable.IsSet && serviceInstance.UpgradeAvailable.Value)
+	plan, err := session.ClientGo.ServicePlans.Get(ctx, serviceInstance.ServicePlanGUID)
+	switch {
+	case err == nil:
+		d.Set("available_maintenance_info_version", plan.MaintenanceInfo.Version)
+	case IsErrNotFound(err) || IsErrForbidden(err):
+		// plan may be removed from the catalog or not visible to the user, maintenance info is then unknown
+		log.Printf("[WARN] Unable to read service plan %s of service instance %s, maintenance info is unknown: %s", serviceInstance.ServicePlanGUID, d.Id(), err)
+		d.Set("available_maintenance_info_version", "")
+	default:
+		return diag.FromErr(err)
+	}
 
 	labels := make(map[string]string)
 
@@ -264,8 +358,15 @@ func resourceServiceInstanceRead(ctx context.Context, d *schema.ResourceData, me
 		}
 
 		d.Set("json_params", params)
+	} else if retrievable, err := isServiceInstanceParamsRetrievable(ctx, session, serviceInstance.ServicePlanGUID); err != nil {
+		log.Printf("[WARN] Unable to know if parameters of service instance %s can be retrieved: %s", d.Id(), err)
+	} else if retrievable {
+		err = readServiceInstanceParams(ctx, d, session)
+		if err != nil {
+			log.Printf("[WARN] Unable to retrieve parameters of service instance %s, keeping current ones: %s", d.Id(), err)
+		}
 	}
-	// Keep state as-is if the cloudcontroller does not return any Parameters (99% of the time)
+	// Keep state as-is if the cloudcontroller does not return any Parameters and broker does not support fetching them
 
 	return nil
 }
@@ -276,18 +377,25 @@ func resourceServiceInstanceUpdate(ctx context.Context, d *schema.ResourceData,
 		return diag.Errorf("client is nil")
 	}
 
+	// Resume an operation interrupted during a previous apply, broker would reject any update meanwhile
+	current, found, err := getServiceInstanceByGUID(session, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if found && isServiceInstanceOperationPending(current.LastOperation) {
+		log.Printf("[INFO] Resuming %s in progress of service instance %s", current.LastOperation.Type, d.Id())
+		// changes are not applied yet, previous values are kept in state to retry them on next apply
+		if diags := waitServiceInstanceOperationDiags(ctx, d, session, d.Timeout(schema.TimeoutUpdate)); diags.HasError() {
+			keepServiceInstancePreviousValues(d)
+			return diags
+		}
+	}
+
 	// Nothing to be done
 	if !isServiceInstanceUpdateRequired(d) {
-		return nil
+		return resourceServiceInstanceUpgrade(ctx, d, session)
 	}
 
-	// Enable partial state mode
-	// We need to explicitly set state updates ourselves or
-	// tell terraform when a state change is applied and thus okay to persist
-	// In particular this is necessary for params since we cannot query CF for
-	// the current value of this field
-	d.Partial(true)
-
 	var (
 		id, name string
 		tags     []string
@@ -297,7 +405,6 @@ func resourceServiceInstanceUpdate(ctx context.Context, d *schema.ResourceData,
 	name = d.Get("name").(string)
 	id = d.Id()
 	jsonParameters := d.Get("json_params").(string)
-	space := d.Get("space").(string)
 	labels := d.Get("labels").(map[string]interface{})
 	metadata := resources.Metadata{
 		Labels: map[string]types.NullString{},
@@ -364,94 +471,130 @@ func resourceServiceInstanceUpdate(ctx context.Context, d *schema.ResourceData,
 	jobURL, _, err := session.ClientV3.UpdateServiceInstance(id, serviceInstanceUpdate)
 	// log.Printf("Service Instance Object Job URL : %+v", JobURL)
 	if err != nil {
+		keepServiceInstancePreviousValues(d)
 		return diag.FromErr(err)
 	}
 
 	// jobURL is empty if no update is required or if the updates were done synchronously
 	if jobURL != "" {
-		// Poll the state of the async job
-		err = common.PollingWithTimeout(func() (bool, error) {
-			job, _, err := session.ClientV3.GetJob(jobURL)
-			if err != nil {
-				return true, err
-			}
+		// an update failed or still in progress keeps previous values in state with the operation,
+		// so that it is resumed or retried on next apply
+		if diags := waitServiceInstanceOperationDiags(ctx, d, session, d.Timeout(schema.TimeoutUpdate)); diags.HasError() {
+			keepServiceInstancePreviousValues(d)
+			return diags
+		}
+	} else if si, found, err := getServiceInstanceByGUID(session, id); err == nil && found {
+		setServiceInstanceLastOperation(d, si.LastOperation)
+	}
 
-			// Stop polling and return error if job failed
-			if job.State == constant.JobFailed {
-				return true, fmt.Errorf(
-					"Instance %s failed %s, reason: %+v",
-					name,
-					space,
-					job.Errors(),
-				)
-			}
-			// If job completed, check if the service instance exists
-			if job.State == constant.JobComplete {
-				si, _, _, err := session.ClientV3.GetServiceInstanceByNameAndSpace(name, space)
-				if err != nil {
-					return true, err
-				}
-				d.SetId(si.GUID)
-				return true, nil
-			}
+	return resourceServiceInstanceUpgrade(ctx, d, session)
+}
 
-			// Last operation initial or inprogress or job not completed, continue polling
-			return false, nil
-		}, 5*time.Second, d.Timeout(schema.TimeoutUpdate))
-		if err != nil {
-			return diag.FromErr(err)
+// resourceServiceInstanceUpgrade upgrades the service instance to the maintenance_info version asked,
+// it is done in its own request as brokers may not support upgrading with other changes
+func resourceServiceInstanceUpgrade(ctx context.Context, d *schema.ResourceData, session *managers.Session) diag.Diagnostics {
+	// current version is taken from cloud controller, an upgrade may have been resumed
+	current, found, err := getServiceInstanceByGUID(session, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if !found {
+		return diag.FromErr(NotFound)
+	}
+	// planned version is only kept once upgrade succeeded
+	d.Set("current_maintenance_info_version", current.MaintenanceInfoVersion)
+	version := serviceInstanceUpgradeVersion(d, current.MaintenanceInfoVersion)
+	if version == "" {
+		return nil
+	}
+	log.Printf("[INFO] Upgrading service instance %s to maintenance_info version %s", d.Id(), version)
+	jobURL, _, err := session.ClientV3.UpdateServiceInstance(d.Id(), resources.ServiceInstance{
+		MaintenanceInfoVersion: version,
+	})
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if jobURL != "" {
+		if diags := waitServiceInstanceOperationDiags(ctx, d, session, d.Timeout(schema.TimeoutUpdate)); diags.HasError() {
+			return diags
 		}
 	}
-
-	// We succeeded, disable partial mode
-	d.Partial(false)
+	d.Set("current_maintenance_info_version", version)
+	d.Set("upgrade_available", false)
 	return nil
 }
 
+// serviceInstanceUpgradeVersion returns the maintenance_info version the service instance must be upgraded to,
+// empty if no upgrade is needed
+func serviceInstanceUpgradeVersion(d resourceGetter, currentVersion string) string {
+	version := d.Get("maintenance_info_version").(string)
+	if d.Get("auto_upgrade").(bool) {
+		version = d.Get("available_maintenance_info_version").(string)
+	}
+	if version == "" || version == currentVersion || isMaintenanceInfoVersionOlder(version, currentVersion) {
+		return ""
+	}
+	return version
+}
+
+// isMaintenanceInfoVersionOlder returns true if the maintenance_info version is older than the current one,
+// versions which are not semver can't be compared and are never considered older
+func isMaintenanceInfoVersionOlder(version string, currentVersion string) bool {
+	if version == "" || currentVersion == "" {
+		return false
+	}
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false
+	}
+	current, err := semver.ParseTolerant(currentVersion)
+	if err != nil {
+		return false
+	}
+	return v.LT(current)
+}
+
+type resourceGetter interface {
+	Get(key string) interface{}
+}
+
 func resourceServiceInstanceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 	id := d.Id()
 
-	jobURL, _, err := session.ClientV3.DeleteServiceInstance(id)
-
+	// Wait for an operation interrupted during a previous apply, a delete may even be already in progress
+	current, found, err := getServiceInstanceByGUID(session, id)
 	if err != nil {
 		return diag.FromErr(err)
 	}
-	name := d.Get("name").(string)
-	space := d.Get("space").(string)
-
-	// Poll the state of the async job
-	err = common.PollingWithTimeout(func() (bool, error) {
-		job, _, err := session.ClientV3.GetJob(jobURL)
-		if err != nil {
-			return true, err
+	if !found {
+		return nil
+	}
+	if isServiceInstanceOperationPending(current.LastOperation) {
+		log.Printf("[INFO] Waiting for %s in progress of service instance %s before deleting", current.LastOperation.Type, id)
+		current, err = waitServiceInstanceOperation(ctx, session, id, d.Timeout(schema.TimeoutDelete))
+		// a failed operation does not prevent deletion
+		if err != nil && isServiceInstanceOperationPending(current.LastOperation) {
+			return diag.FromErr(err)
 		}
-
-		// Stop polling and return error if job failed
-		if job.State == constant.JobFailed {
-			return true, fmt.Errorf(
-				"Instance %s failed %s, reason: %+v",
-				name,
-				space,
-				job.Errors(),
-			)
+		if current.GUID == "" {
+			return nil
 		}
+	}
 
-		if job.State == constant.JobComplete {
-			_, _, _, err := session.ClientV3.GetServiceInstances(ccv3.Query{
-				Key:    ccv3.GUIDFilter,
-				Values: []string{id},
-			})
-			if err != nil && !IsErrNotFound(err) {
-				return true, err
-			}
-			return true, nil
-		}
-		// Last operation initial or inprogress or job not completed, continue polling
-		return false, nil
-	}, 5*time.Second, d.Timeout(schema.TimeoutDelete))
+	_, _, err = session.ClientV3.DeleteServiceInstance(id)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 
-	return diag.FromErr(err)
+	current, err = waitServiceInstanceOperation(ctx, session, id, d.Timeout(schema.TimeoutDelete))
+	if err != nil {
+		if current.GUID != "" {
+			setServiceInstanceLastOperation(d, current.LastOperation)
+		}
+		return diag.FromErr(err)
+	}
+	return nil
 }
 
 func resourceServiceInstanceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
@@ -495,6 +638,180 @@ func resourceServiceInstanceImport(ctx context.Context, d *schema.ResourceData,
 	return ImportReadContext(resourceServiceInstanceRead)(ctx, d, meta)
 }
 
+// keepServiceInstancePreviousValues sets back in state values of an update not applied by the broker,
+// the update is then planned again on next apply
+func keepServiceInstancePreviousValues(d *schema.ResourceData) {
+	for _, key := range []string{"name", "service_plan", "json_params", "tags", "labels"} {
+		old, _ := d.GetChange(key)
+		d.Set(key, old)
+	}
+}
+
 func isServiceInstanceUpdateRequired(d ResourceChanger) bool {
 	return d.HasChange("name") || d.HasChange("service_plan") || d.HasChange("json_params") || d.HasChange("tags") || d.HasChange("labels")
 }
+
+// isServiceInstanceParamsRetrievable returns true if broker of the plan's service offering
+// supports fetching service instance parameters (instances_retrievable)
+func isServiceInstanceParamsRetrievable(ctx context.Context, session *managers.Session, servicePlanGUID string) (bool, error) {
+	_, offering, err := session.ClientGo.ServicePlans.GetIncludeServicePlan(ctx, servicePlanGUID)
+	if err != nil {
+		return false, err
+	}
+	return offering.BrokerCatalog.Features.InstancesRetrievable, nil
+}
+
+// readServiceInstanceParams sets json_params from parameters fetched from the broker,
+// current value is kept when it is semantically the same to avoid spurious diff
+func readServiceInstanceParams(ctx context.Context, d *schema.ResourceData, session *managers.Session) error {
+	rawParams, err := session.ClientGo.ServiceInstances.GetManagedParameters(ctx, d.Id())
+	if err != nil {
+		return err
+	}
+	params, err := normalizeJSONParams(string(*rawParams))
+	if err != nil {
+		return err
+	}
+	current, err := normalizeJSONParams(d.Get("json_params").(string))
+	if err != nil {
+		return err
+	}
+	if params != current {
+		d.Set("json_params", params)
+	}
+	return nil
+}
+
+// normalizeJSONParams returns json with sorted keys and without spaces, empty or null object is returned as empty string
+func normalizeJSONParams(jsonParams string) (string, error) {
+	if strings.TrimSpace(jsonParams) == "" {
+		return "", nil
+	}
+	var params interface{}
+	err := json.Unmarshal([]byte(jsonParams), &params)
+	if err != nil {
+		return "", err
+	}
+	if m, ok := params.(map[string]interface{}); params == nil || (ok && len(m) == 0) {
+		return "", nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func getServiceInstanceByGUID(session *managers.Session, guid string) (resources.ServiceInstance, bool, error) {
+	serviceInstances, _, _, err := session.ClientV3.GetServiceInstances(ccv3.Query{
+		Key:    ccv3.GUIDFilter,
+		Values: []string{guid},
+	})
+	if err != nil || len(serviceInstances) == 0 {
+		return resources.ServiceInstance{}, false, err
+	}
+	return serviceInstances[0], true, nil
+}
+
+func getServiceInstanceByNameAndSpace(session *managers.Session, name string, spaceGUID string) (resources.ServiceInstance, bool, error) {
+	serviceInstances, _, _, err := session.ClientV3.GetServiceInstances(ccv3.Query{
+		Key:    ccv3.NameFilter,
+		Values: []string{name},
+	}, ccv3.Query{
+		Key:    ccv3.SpaceGUIDFilter,
+		Values: []string{spaceGUID},
+	})
+	if err != nil || len(serviceInstances) == 0 {
+		return resources.ServiceInstance{}, false, err
+	}
+	return serviceInstances[0], true, nil
+}
+
+// isServiceInstanceOperationPending returns true if broker has not finished the last operation
+func isServiceInstanceOperationPending(lastOperation resources.LastOperation) bool {
+	return lastOperation.State == resources.OperationInProgress || lastOperation.State == "initial"
+}
+
+// isServiceInstanceLastOperationPending is isServiceInstanceOperationPending on last_operation from state
+func isServiceInstanceLastOperationPending(v interface{}) bool {
+	lastOperations := GetListOfStructs(v)
+	if len(lastOperations) == 0 {
+		return false
+	}
+	return isServiceInstanceOperationPending(resources.LastOperation{
+		State: resources.LastOperationState(lastOperations[0]["state"].(string)),
+	})
+}
+
+func setServiceInstanceLastOperation(d *schema.ResourceData, lastOperation resources.LastOperation) {
+	d.Set("last_operation", []map[string]interface{}{
+		{
+			"type":        string(lastOperation.Type),
+			"state":       string(lastOperation.State),
+			"description": lastOperation.Description,
+		},
+	})
+}
+
+// waitServiceInstanceOperation polls the last operation of the service instance until broker finishes it,
+// polling stops on timeout or when terraform is interrupted, returned instance is empty if it has been deleted
+func waitServiceInstanceOperation(ctx context.Context, session *managers.Session, guid string, timeout time.Duration) (resources.ServiceInstance, error) {
+	var serviceInstance resources.ServiceInstance
+	ctxs := []context.Context{ctx}
+	if stopCtx, ok := ctx.Value(schema.StopContextKey).(context.Context); ok {
+		ctxs = append(ctxs, stopCtx)
+	}
+	err := common.PollingWithContext(func() (bool, error) {
+		si, found, err := getServiceInstanceByGUID(session, guid)
+		if err != nil {
+			return true, err
+		}
+		if !found {
+			serviceInstance = resources.ServiceInstance{}
+			return true, nil
+		}
+		serviceInstance = si
+		if isServiceInstanceOperationPending(si.LastOperation) {
+			return false, nil
+		}
+		if si.LastOperation.State == resources.OperationFailed {
+			return true, fmt.Errorf(
+				"Service instance %s %s failed, reason: %s",
+				si.Name,
+				si.LastOperation.Type,
+				si.LastOperation.Description,
+			)
+		}
+		return true, nil
+	}, 5*time.Second, timeout, ctxs...)
+	return serviceInstance, err
+}
+
+// waitServiceInstanceOperationDiags waits for the operation on the service instance and stores it in state,
+// an operation still in progress at timeout or interruption is kept in state as last_operation
+// to be resumed on next apply
+func waitServiceInstanceOperationDiags(ctx context.Context, d *schema.ResourceData, session *managers.Session, timeout time.Duration) diag.Diagnostics {
+	serviceInstance, err := waitServiceInstanceOperation(ctx, session, d.Id(), timeout)
+	if err != nil && serviceInstance.GUID == "" {
+		// waiting may stop before the instance is polled once, its operation is still needed to be resumed
+		if si, found, getErr := getServiceInstanceByGUID(session, d.Id()); getErr == nil && found {
+			serviceInstance = si
+		}
+	}
+	if serviceInstance.GUID != "" {
+		setServiceInstanceLastOperation(d, serviceInstance.LastOperation)
+	}
+	if err == nil {
+		return nil
+	}
+	if isServiceInstanceOperationPending(serviceInstance.LastOperation) {
+		return diag.Diagnostics{
+			diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Service instance %s %s still in progress", serviceInstance.Name, serviceInstance.LastOperation.Type),
+				Detail:   fmt.Sprintf("Stopped waiting for the broker (%s), the operation will be resumed on next apply.", err),
+			},
+		}
+	}
+	return diag.FromErr(err)
+}
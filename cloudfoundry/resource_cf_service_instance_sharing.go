@@ -2,7 +2,10 @@ package cloudfoundry
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/cloudfoundry/go-cfclient/v3/client"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
@@ -12,8 +15,13 @@ func resourceServiceInstanceSharing() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceServiceInstanceSharingCreate,
 		ReadContext:   resourceServiceInstanceSharingRead,
+		UpdateContext: resourceServiceInstanceSharingUpdate,
 		DeleteContext: resourceServiceInstanceSharingDelete,
 
+		Importer: &schema.ResourceImporter{
+			StateContext: ImportReadContext(resourceServiceInstanceSharingRead),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"service_instance_id": {
 				Type:        schema.TypeString,
@@ -22,10 +30,24 @@ func resourceServiceInstanceSharing() *schema.Resource {
 				Description: "The ID of the service instance to share",
 			},
 			"space_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "The ID of the space to share the service instance with, the space can be in the same or different org",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ForceNew:     true,
+				ExactlyOneOf: []string{"space_id", "space_ids"},
+				Description:  "The ID of the space to share the service instance with, the space can be in the same or different org",
+			},
+			"space_ids": {
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Set:         schema.HashString,
+				Description: "The IDs of every space the service instance is shared with, spaces can be in the same or different orgs",
+			},
+			"force_unshare": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Unshare even if apps or service keys in the target space use the service instance, their bindings are deleted by cloud controller",
 			},
 		},
 	}
@@ -34,6 +56,28 @@ func resourceServiceInstanceSharing() *schema.Resource {
 func resourceServiceInstanceSharingRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
+	// a single space is shared when id is composed of service instance and space
+	if strings.Contains(d.Id(), "/") {
+		return resourceServiceInstanceSharingReadSpace(ctx, d, meta)
+	}
+
+	sharedSpaces, err := serviceInstanceSharedSpaces(session, d.Id())
+	if err != nil {
+		if IsErrNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+
+	d.Set("service_instance_id", d.Id())
+	d.Set("space_ids", sharedSpaces)
+	return nil
+}
+
+func resourceServiceInstanceSharingReadSpace(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
 	serviceID, spaceID, err := parseID(d.Id())
 	if err != nil {
 		return diag.FromErr(err)
@@ -66,6 +110,20 @@ func resourceServiceInstanceSharingRead(ctx context.Context, d *schema.ResourceD
 func resourceServiceInstanceSharingCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 	serviceID := d.Get("service_instance_id").(string)
+
+	if spaceIDs, ok := d.GetOk("space_ids"); ok {
+		spaces := make([]string, 0)
+		for _, spaceID := range spaceIDs.(*schema.Set).List() {
+			spaces = append(spaces, spaceID.(string))
+		}
+		err := shareServiceInstance(session, serviceID, spaces)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.SetId(serviceID)
+		return resourceServiceInstanceSharingRead(ctx, d, meta)
+	}
+
 	spaceID := d.Get("space_id").(string)
 
 	spacesGUIDList, _, err := session.ClientV3.ShareServiceInstanceToSpaces(serviceID, []string{spaceID})
@@ -82,11 +140,134 @@ func resourceServiceInstanceSharingCreate(ctx context.Context, d *schema.Resourc
 	return nil
 }
 
+func resourceServiceInstanceSharingUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	serviceID := d.Get("service_instance_id").(string)
+
+	if d.HasChange("space_ids") {
+		removed, added := getListChanges(d.GetChange("space_ids"))
+		err := unshareServiceInstance(ctx, session, serviceID, removed, d.Get("force_unshare").(bool))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		err = shareServiceInstance(session, serviceID, added)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceServiceInstanceSharingRead(ctx, d, meta)
+}
+
 func resourceServiceInstanceSharingDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 	serviceID := d.Get("service_instance_id").(string)
-	spaceID := d.Get("space_id").(string)
-	_, err := session.ClientV3.UnshareServiceInstanceFromSpace(serviceID, spaceID)
 
+	spaceIDs := make([]string, 0)
+	for _, spaceID := range d.Get("space_ids").(*schema.Set).List() {
+		spaceIDs = append(spaceIDs, spaceID.(string))
+	}
+	if spaceID := d.Get("space_id").(string); spaceID != "" {
+		spaceIDs = []string{spaceID}
+	}
+	err := unshareServiceInstance(ctx, session, serviceID, spaceIDs, d.Get("force_unshare").(bool))
 	return diag.FromErr(err)
 }
+
+func serviceInstanceSharedSpaces(session *managers.Session, serviceID string) (*schema.Set, error) {
+	spaceWithOrganizationList, _, err := session.ClientV3.GetServiceInstanceSharedSpaces(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	spaces := schema.NewSet(schema.HashString, []interface{}{})
+	for _, spaceWithOrganization := range spaceWithOrganizationList {
+		spaces.Add(spaceWithOrganization.SpaceGUID)
+	}
+	return spaces, nil
+}
+
+func shareServiceInstance(session *managers.Session, serviceID string, spaceIDs []string) error {
+	if len(spaceIDs) == 0 {
+		return nil
+	}
+	_, _, err := session.ClientV3.ShareServiceInstanceToSpaces(serviceID, spaceIDs)
+	return err
+}
+
+// unshareServiceInstance unshares service instance from spaces, cloud controller deletes bindings of apps
+// in a space when unsharing from it, so it fails when an app is bound or a service key was created
+// in any space unless force is set.
+func unshareServiceInstance(ctx context.Context, session *managers.Session, serviceID string, spaceIDs []string, force bool) error {
+	if len(spaceIDs) == 0 {
+		return nil
+	}
+	if !force {
+		usage, err := session.ClientGo.ServiceInstances.GetSharedSpaceUsageSummary(ctx, serviceID)
+		if err != nil {
+			if IsErrNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		boundApps := make(map[string]int)
+		for _, u := range usage.UsageSummary {
+			boundApps[u.Space.GUID] = u.BoundAppCount
+		}
+		keys, err := serviceInstanceKeysBySpace(ctx, session, serviceID, spaceIDs)
+		if err != nil {
+			return err
+		}
+		inUse := make([]string, 0)
+		for _, spaceID := range spaceIDs {
+			if count := boundApps[spaceID]; count > 0 || keys[spaceID] > 0 {
+				inUse = append(inUse, fmt.Sprintf("%s (%d bound apps, %d service keys)", spaceID, count, keys[spaceID]))
+			}
+		}
+		if len(inUse) > 0 {
+			return fmt.Errorf(
+				"service instance %s is still used by apps or service keys in spaces %s, unsharing would delete their bindings: "+
+					"unbind them first or set force_unshare to true",
+				serviceID, strings.Join(inUse, ", "),
+			)
+		}
+	}
+
+	for _, spaceID := range spaceIDs {
+		_, err := session.ClientV3.UnshareServiceInstanceFromSpace(serviceID, spaceID)
+		if err != nil && !IsErrNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceInstanceKeysBySpace counts service keys of the service instance created in the given spaces,
+// keys are not related to a space by cloud controller so their space is found from their creation event
+func serviceInstanceKeysBySpace(ctx context.Context, session *managers.Session, serviceID string, spaceIDs []string) (map[string]int, error) {
+	bindingOpts := client.NewServiceCredentialBindingListOptions()
+	bindingOpts.ServiceInstanceGUIDs = client.Filter{Values: []string{serviceID}}
+	bindingOpts.Type = client.Filter{Values: []string{"key"}}
+	keys, err := session.ClientGo.ServiceCredentialBindings.ListAll(ctx, bindingOpts)
+	if err != nil {
+		return nil, err
+	}
+	count := make(map[string]int)
+	if len(keys) == 0 {
+		return count, nil
+	}
+	keyGUIDs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		keyGUIDs = append(keyGUIDs, key.GUID)
+	}
+	eventOpts := client.NewAuditEventListOptions()
+	eventOpts.Types = client.Filter{Values: []string{"audit.service_key.create"}}
+	eventOpts.TargetGUIDs = client.ExclusionFilter{Filter: client.Filter{Values: keyGUIDs}}
+	eventOpts.SpaceGUIDs = client.Filter{Values: spaceIDs}
+	events, err := session.ClientGo.AuditEvents.ListAll(ctx, eventOpts)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		count[event.Space.GUID]++
+	}
+	return count, nil
+}
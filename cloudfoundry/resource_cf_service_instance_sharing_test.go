@@ -47,6 +47,52 @@ resource "cloudfoundry_service_instance_sharing" "test-service-instance-sharing"
 }
 `
 
+const serviceInstanceSharingSpaces = `
+data "cloudfoundry_service" "test-service" {
+  name = "%s"
+}
+
+data "cloudfoundry_user" "u"{
+	name = "%s"
+	org_id = "%s"
+}
+
+resource "cloudfoundry_space" "test-space-1" {
+	name = "space-1-%s"
+	org = "%s"
+	managers = [ data.cloudfoundry_user.u.id ]
+	developers = [ data.cloudfoundry_user.u.id ]
+	auditors = [ data.cloudfoundry_user.u.id ]
+}
+
+resource "cloudfoundry_space" "test-space-2" {
+	name = "space-2-%s"
+	org = "%s"
+	managers = [ data.cloudfoundry_user.u.id ]
+	developers = [ data.cloudfoundry_user.u.id ]
+	auditors = [ data.cloudfoundry_user.u.id ]
+}
+
+resource "cloudfoundry_space" "test-space-3" {
+	name = "space-3-%s"
+	org = "%s"
+	managers = [ data.cloudfoundry_user.u.id ]
+	developers = [ data.cloudfoundry_user.u.id ]
+	auditors = [ data.cloudfoundry_user.u.id ]
+}
+
+resource "cloudfoundry_service_instance" "test-service-instance" {
+	name = "test-service-instance-sharing-%s"
+	space = resource.cloudfoundry_space.test-space-1.id
+	service_plan = data.cloudfoundry_service.test-service.service_plans["%s"]
+}
+
+resource "cloudfoundry_service_instance_sharing" "test-service-instance-sharing" {
+	service_instance_id = resource.cloudfoundry_service_instance.test-service-instance.id
+	space_ids = [%s]
+}
+`
+
 func TestAccResServiceInstanceSharing_normal(t *testing.T) {
 	t.Parallel()
 	orgId, _ := defaultTestOrg(t)
@@ -85,3 +131,53 @@ func TestAccResServiceInstanceSharing_normal(t *testing.T) {
 			},
 		})
 }
+
+func TestAccResServiceInstanceSharing_spaces(t *testing.T) {
+	t.Parallel()
+	orgId, _ := defaultTestOrg(t)
+
+	serviceName, _, servicePlan := getTestServiceBrokers(t)
+	userName := testSession().Config.User
+
+	testId := guuid.New().String()
+
+	ref := "cloudfoundry_service_instance_sharing.test-service-instance-sharing"
+	config := func(spaces string) string {
+		return fmt.Sprintf(serviceInstanceSharingSpaces, serviceName, userName, orgId,
+			testId, orgId, testId, orgId, testId, orgId, testId, servicePlan, spaces)
+	}
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy: resource.ComposeTestCheckFunc(
+				testAccCheckSpaceDestroyed(fmt.Sprintf("space-1-%s", testId)),
+				testAccCheckSpaceDestroyed(fmt.Sprintf("space-2-%s", testId)),
+				testAccCheckSpaceDestroyed(fmt.Sprintf("space-3-%s", testId)),
+			),
+			Steps: []resource.TestStep{
+				{
+					Config: config("cloudfoundry_space.test-space-2.id, cloudfoundry_space.test-space-3.id"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr(ref, "space_ids.#", "2"),
+						resource.TestCheckTypeSetElemAttrPair(ref, "space_ids.*", "cloudfoundry_space.test-space-2", "id"),
+						resource.TestCheckTypeSetElemAttrPair(ref, "space_ids.*", "cloudfoundry_space.test-space-3", "id"),
+					),
+				},
+				{
+					ResourceName:            ref,
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"force_unshare"},
+				},
+				{
+					Config: config("cloudfoundry_space.test-space-3.id"),
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr(ref, "space_ids.#", "1"),
+						resource.TestCheckTypeSetElemAttrPair(ref, "space_ids.*", "cloudfoundry_space.test-space-3", "id"),
+					),
+				},
+			},
+		})
+}
@@ -1,8 +1,12 @@
 package cloudfoundry
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -10,7 +14,9 @@ import (
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
 )
 
@@ -136,6 +142,10 @@ func TestAccResServiceInstance_normal(t *testing.T) {
 							ref, "tags.1", "tag-2"),
 						resource.TestCheckResourceAttr(
 							ref, "labels.instance-name", "test-service-instance"),
+						resource.TestCheckResourceAttr(
+							ref, "upgrade_available", "false"),
+						resource.TestCheckResourceAttrPair(
+							ref, "current_maintenance_info_version", ref, "available_maintenance_info_version"),
 					),
 				},
 
@@ -169,6 +179,57 @@ func TestAccResServiceInstance_normal(t *testing.T) {
 		})
 }
 
+const serviceInstanceResourceParams = `
+data "cloudfoundry_service" "test-service" {
+  name = "%s"
+}
+
+resource "cloudfoundry_service_instance" "test-service-instance-params" {
+  name = "test-service-instance-params"
+  space = "%s"
+  service_plan = "${data.cloudfoundry_service.test-service.service_plans["%s"]}"
+  json_params = <<EOT
+%s
+EOT
+}
+`
+
+func TestAccResServiceInstance_jsonParams(t *testing.T) {
+
+	spaceId, _ := defaultTestSpace(t)
+	serviceName1, _, servicePlan := getTestServiceBrokers(t)
+
+	ref := "cloudfoundry_service_instance.test-service-instance-params"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy: testAccCheckServiceInstanceDestroyed(
+				[]string{"test-service-instance-params"},
+				ref),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceInstanceResourceParams,
+						serviceName1, spaceId, servicePlan, `{"key1": "value1", "key2": {"nested": true}}`,
+					),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServiceInstanceExists(ref),
+						resource.TestCheckResourceAttrSet(ref, "json_params"),
+					),
+				},
+				// same parameters in another layout must not show any diff
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceInstanceResourceParams,
+						serviceName1, spaceId, servicePlan, `{ "key2": { "nested": true }, "key1": "value1" }`,
+					),
+					PlanOnly: true,
+				},
+			},
+		})
+}
+
 func TestAccResServiceInstances_withFakePlans(t *testing.T) {
 
 	spaceId, _ := defaultTestSpace(t)
@@ -199,6 +260,8 @@ func TestAccResServiceInstances_withFakePlans(t *testing.T) {
 						// test fake-async-plan
 						testAccCheckServiceInstanceExists(refFakeAsyncPlan),
 						resource.TestCheckResourceAttr(refFakeAsyncPlan, "name", "fake-service-instance-with-fake-async-plan"),
+						resource.TestCheckResourceAttr(refFakeAsyncPlan, "last_operation.0.type", "create"),
+						resource.TestCheckResourceAttr(refFakeAsyncPlan, "last_operation.0.state", "succeeded"),
 					),
 				},
 			},
@@ -293,3 +356,144 @@ func testAccCheckServiceInstanceDestroyed(names []string, testResource string) r
 		return nil
 	}
 }
+
+const serviceInstanceCreating = `{
+  "guid": "si-guid",
+  "name": "test-db",
+  "type": "managed",
+  "last_operation": {"type": "create", "state": "in progress", "description": "provisioning"},
+  "relationships": {
+    "space": {"data": {"guid": "space-guid"}},
+    "service_plan": {"data": {"guid": "plan-guid"}}
+  }
+}`
+
+// newServiceInstanceTestSession gives a session on a cloud controller stand-in creating the given service instance
+func newServiceInstanceTestSession(t *testing.T, instance string) *managers.Session {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/":
+			fmt.Fprintf(w, `{"links":{"cloud_controller_v3":{"href":"%s/v3"}}}`, server.URL)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3":
+			fmt.Fprintf(w, `{"links":{"service_instances":{"href":"%s/v3/service_instances"}}}`, server.URL)
+		case r.Method == http.MethodPost && r.URL.Path == "/v3/service_instances":
+			w.Header().Set("Location", server.URL+"/v3/jobs/job-guid")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodGet && r.URL.Path == "/v3/service_instances":
+			fmt.Fprintf(w, `{"pagination":{"next":null},"resources":[%s]}`, instance)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := ccv3.NewClient(ccv3.Config{AppName: "test", AppVersion: "test"})
+	if _, _, err := client.TargetCF(ccv3.TargetSettings{URL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	return &managers.Session{ClientV3: client}
+}
+
+func TestServiceInstanceCreateInterrupted(t *testing.T) {
+	session := newServiceInstanceTestSession(t, serviceInstanceCreating)
+	config := map[string]interface{}{
+		"name":         "test-db",
+		"space":        "space-guid",
+		"service_plan": "plan-guid",
+	}
+	d := schema.TestResourceDataRaw(t, resourceServiceInstance().Schema, config)
+
+	// terraform is interrupted while the broker is still provisioning
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	diags := resourceServiceInstanceCreate(ctx, d, session)
+	if diags.HasError() {
+		t.Fatalf("creation in progress must not fail and taint the instance, got %#v", diags)
+	}
+	if len(diags) != 1 || diags[0].Severity != diag.Warning {
+		t.Fatalf("expected a warning about the creation in progress, got %#v", diags)
+	}
+	if d.Id() != "si-guid" {
+		t.Fatalf("expected instance to be kept in state, got id %q", d.Id())
+	}
+	if !isServiceInstanceLastOperationPending(d.Get("last_operation")) {
+		t.Fatalf("expected last_operation in progress, got %#v", d.Get("last_operation"))
+	}
+
+	// next apply plans an update resuming the wait instead of a replacement
+	diff, err := resourceServiceInstance().Diff(context.Background(), d.State(), terraform.NewResourceConfigRaw(config), session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff == nil || diff.RequiresNew() {
+		t.Fatalf("expected an in-place update, got %#v", diff)
+	}
+	if lastOperation := diff.Attributes["last_operation.#"]; lastOperation == nil || !lastOperation.NewComputed {
+		t.Fatalf("expected last_operation to be planned as computed, got %#v", diff.Attributes)
+	}
+}
+
+func TestServiceInstanceMaintenanceInfoVersionDiff(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "si-guid",
+		Attributes: map[string]string{
+			"name":                               "test-db",
+			"space":                              "space-guid",
+			"service_plan":                       "plan-guid",
+			"current_maintenance_info_version":   "1.2.0",
+			"available_maintenance_info_version": "2.0.0",
+		},
+	}
+	cases := map[string]struct {
+		config  map[string]interface{}
+		version string
+		err     string
+	}{
+		"pinned_newer_version_upgrades": {
+			config:  map[string]interface{}{"maintenance_info_version": "1.3.0"},
+			version: "1.3.0",
+		},
+		"auto_upgrade_to_available_version": {
+			config:  map[string]interface{}{"auto_upgrade": true},
+			version: "2.0.0",
+		},
+		"pinned_current_version_does_nothing": {
+			config: map[string]interface{}{"maintenance_info_version": "1.2.0"},
+		},
+		"pinned_older_version_is_rejected": {
+			config: map[string]interface{}{"maintenance_info_version": "1.1.9"},
+			err:    "can't be downgraded",
+		},
+	}
+
+	for tn, tc := range cases {
+		config := map[string]interface{}{
+			"name":         "test-db",
+			"space":        "space-guid",
+			"service_plan": "plan-guid",
+		}
+		for k, v := range tc.config {
+			config[k] = v
+		}
+		diff, err := resourceServiceInstance().Diff(context.Background(), state, terraform.NewResourceConfigRaw(config), nil)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("bad: %s, expected error containing %q, got %v", tn, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("bad: %s, err: %#v", tn, err)
+		}
+		var version string
+		if diff != nil && diff.Attributes["current_maintenance_info_version"] != nil {
+			version = diff.Attributes["current_maintenance_info_version"].New
+		}
+		if version != tc.version {
+			t.Fatalf("bad: %s, expected upgrade to %q, got %q", tn, tc.version, version)
+		}
+	}
+}
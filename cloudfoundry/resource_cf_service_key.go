@@ -2,6 +2,7 @@ package cloudfoundry
 
 import (
 	"context"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"code.cloudfoundry.org/cli/types"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
@@ -25,6 +27,7 @@ func resourceServiceKey() *schema.Resource {
 
 		CreateContext: resourceServiceKeyCreate,
 		ReadContext:   resourceServiceKeyRead,
+		UpdateContext: resourceServiceKeyUpdate,
 		DeleteContext: resourceServiceKeyDelete,
 
 		Importer: &schema.ResourceImporter{
@@ -33,6 +36,7 @@ func resourceServiceKey() *schema.Resource {
 
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(60 * time.Second),
+			Update: schema.DefaultTimeout(120 * time.Second),
 			Delete: schema.DefaultTimeout(60 * time.Second),
 		},
 
@@ -68,24 +72,146 @@ func resourceServiceKey() *schema.Resource {
 				Computed:  true,
 				Sensitive: true,
 			},
+			"rotation_trigger": &schema.Schema{
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Any change of this value creates a new key, previous key is kept during the grace period",
+			},
+			"rotation_grace_period": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validateServiceKeyGracePeriod,
+				Description:  "Duration the previous key is kept after a rotation (e.g. 24h), previous key is deleted on next apply when not set",
+			},
+			"previous_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"previous_credentials": &schema.Schema{
+				Type:      schema.TypeMap,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"previous_expires_at": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
+
+		CustomizeDiff: customdiff.All(
+			resourceServiceKeyCustomizeDiff,
+			resourceServiceKeyRotationCustomizeDiff,
+		),
+	}
+}
+
+// resourceServiceKeyCustomizeDiff validates params against the binding schema of the service instance plan
+func resourceServiceKeyCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() != "" && !d.HasChanges("params", "params_json", "service_instance") {
+		return nil
+	}
+	if !d.NewValueKnown("service_instance") || !d.NewValueKnown("params") || !d.NewValueKnown("params_json") {
+		return nil
+	}
+	params, ok, err := serviceParamsFromConfig(d.Get("params").(map[string]interface{}), d.Get("params_json").(string))
+	if err != nil || !ok {
+		return nil
+	}
+	session := meta.(*managers.Session)
+	planGUID, err := serviceInstancePlanGUID(ctx, session, d.Get("service_instance").(string))
+	if err != nil || planGUID == "" {
+		return nil
+	}
+	attr := "params"
+	if len(d.Get("params").(map[string]interface{})) == 0 {
+		attr = "params_json"
+	}
+	return validateServiceParams(ctx, session, planGUID, servicePlanSchemaBindingCreate, attr, params)
+}
+
+// resourceServiceKeyRotationCustomizeDiff plans a new key when rotation trigger changes
+// and the deletion of the previous key once its grace period is over
+func resourceServiceKeyRotationCustomizeDiff(ctx context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" {
+		return nil
+	}
+	if d.HasChange("rotation_trigger") {
+		for _, key := range []string{"credentials", "previous_id", "previous_credentials", "previous_expires_at"} {
+			if err := d.SetNewComputed(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if d.Get("previous_id").(string) == "" || !serviceKeyPreviousExpired(d.Get("previous_expires_at").(string)) {
+		return nil
+	}
+	if err := d.SetNew("previous_id", ""); err != nil {
+		return err
+	}
+	if err := d.SetNew("previous_credentials", map[string]interface{}{}); err != nil {
+		return err
+	}
+	return d.SetNew("previous_expires_at", "")
+}
+
+func validateServiceKeyGracePeriod(v interface{}, k string) (ws []string, errs []error) {
+	duration, err := time.ParseDuration(v.(string))
+	if err != nil || duration < 0 {
+		errs = append(errs, fmt.Errorf("%q must be a positive duration such as '30m' or '24h'", k))
 	}
+	return ws, errs
+}
+
+// serviceKeyPreviousExpired returns true when grace period of previous key is over,
+// previous key without expiration is kept until next apply
+func serviceKeyPreviousExpired(expiresAt string) bool {
+	if expiresAt == "" {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return true
+	}
+	return time.Now().After(t)
+}
+
+// serviceKeyRotatedName returns name of a key created by a rotation, key names must be unique
+// for a service instance and previous key still exists when the new one is created
+func serviceKeyRotatedName(name, trigger string) string {
+	return fmt.Sprintf("%s-%x", name, sha1.Sum([]byte(trigger)))[:len(name)+9]
 }
 
 func resourceServiceKeyCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
-	name := d.Get("name").(string)
-	serviceInstance := d.Get("service_instance").(string)
+	params, err := serviceKeyParams(d)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	guid, credentials, err := createServiceKey(session, d.Get("service_instance").(string), d.Get("name").(string), params, d.Timeout(schema.TimeoutCreate))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.Set("credentials", credentials)
+	d.SetId(guid)
+	return nil
+}
+
+func serviceKeyParams(d *schema.ResourceData) (map[string]interface{}, error) {
 	params := d.Get("params").(map[string]interface{})
 	paramJson := d.Get("params_json").(string)
 	if len(params) == 0 && paramJson != "" {
 		err := json.Unmarshal([]byte(paramJson), &params)
 		if err != nil {
-			return diag.FromErr(err)
+			return nil, err
 		}
 	}
+	return params, nil
+}
 
+// createServiceKey creates a key and waits for the broker to bind it, it returns guid and normalized credentials of the key
+func createServiceKey(session *managers.Session, serviceInstance, name string, params map[string]interface{}, timeout time.Duration) (string, map[string]interface{}, error) {
 	binding := resources.ServiceCredentialBinding{
 		ServiceInstanceGUID: serviceInstance,
 		Name:                name,
@@ -95,7 +221,7 @@ func resourceServiceKeyCreate(ctx context.Context, d *schema.ResourceData, meta
 
 	jobURL, _, err := session.ClientV3.CreateServiceCredentialBinding(binding)
 	if err != nil {
-		return diag.FromErr(err)
+		return "", nil, err
 	}
 
 	var serviceKey resources.ServiceCredentialBinding
@@ -151,18 +277,16 @@ func resourceServiceKeyCreate(ctx context.Context, d *schema.ResourceData, meta
 
 		// Last operation initial or inprogress or job not completed, continue polling
 		return false, nil
-	}, 5*time.Second, d.Timeout(schema.TimeoutCreate))
+	}, 5*time.Second, timeout)
 	if err != nil {
-		return diag.FromErr(err)
+		return "", nil, err
 	}
 
 	credentials, _, err := session.ClientV3.GetServiceCredentialBindingDetails(serviceKey.GUID)
 	if err != nil {
-		return diag.FromErr(err)
+		return "", nil, err
 	}
-	d.Set("credentials", normalizeMap(credentials.Credentials, make(map[string]interface{}), "", "_"))
-	d.SetId(serviceKey.GUID)
-	return nil
+	return serviceKey.GUID, normalizeMap(credentials.Credentials, make(map[string]interface{}), "", "_"), nil
 }
 
 func resourceServiceKeyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -197,7 +321,11 @@ func resourceServiceKeyRead(ctx context.Context, d *schema.ResourceData, meta in
 	if len(serviceKeys) != 1 {
 		return diag.FromErr(fmt.Errorf("Some thing went wrong"))
 	}
-	d.Set("name", serviceKeys[0].Name)
+	// a rotated key is named after the key name and the rotation trigger
+	name := d.Get("name").(string)
+	if serviceKeys[0].Name != name && serviceKeys[0].Name != serviceKeyRotatedName(name, d.Get("rotation_trigger").(string)) {
+		d.Set("name", serviceKeys[0].Name)
+	}
 	d.Set("service_instance", serviceKeys[0].ServiceInstanceGUID)
 	d.SetId(serviceKeys[0].GUID)
 	serviceKeyDetails, _, err := session.ClientV3.GetServiceCredentialBindingDetails(d.Id())
@@ -205,27 +333,98 @@ func resourceServiceKeyRead(ctx context.Context, d *schema.ResourceData, meta in
 		return diag.FromErr(err)
 	}
 	d.Set("credentials", normalizeMap(serviceKeyDetails.Credentials, make(map[string]interface{}), "", "_"))
+
+	if previousID := d.Get("previous_id").(string); previousID != "" {
+		previousDetails, _, err := session.ClientV3.GetServiceCredentialBindingDetails(previousID)
+		if err != nil {
+			if !IsErrNotFound(err) {
+				return diag.FromErr(err)
+			}
+			log.Printf("[WARN] previous service key %s has been deleted outside of terraform", previousID)
+			d.Set("previous_id", "")
+			d.Set("previous_credentials", map[string]interface{}{})
+			d.Set("previous_expires_at", "")
+			return nil
+		}
+		d.Set("previous_credentials", normalizeMap(previousDetails.Credentials, make(map[string]interface{}), "", "_"))
+	}
 	return nil
 }
 
+// resourceServiceKeyUpdate rotates the key, new key is created before the previous one is deleted
+// so consumers can switch to the new credentials during the grace period
+func resourceServiceKeyUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	if d.HasChange("rotation_trigger") {
+		// only one previous key is kept, the one of the last rotation is deleted first
+		oldPrevious, _ := d.GetChange("previous_id")
+		if oldPrevious.(string) != "" {
+			if err := deleteServiceKey(session, oldPrevious.(string), d.Timeout(schema.TimeoutUpdate)); err != nil && !IsErrNotFound(err) {
+				return diag.FromErr(err)
+			}
+		}
+
+		params, err := serviceKeyParams(d)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		name := serviceKeyRotatedName(d.Get("name").(string), d.Get("rotation_trigger").(string))
+		guid, credentials, err := createServiceKey(session, d.Get("service_instance").(string), name, params, d.Timeout(schema.TimeoutUpdate))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+
+		oldCredentials, _ := d.GetChange("credentials")
+		d.Set("previous_id", d.Id())
+		d.Set("previous_credentials", oldCredentials)
+		expiresAt := ""
+		if gracePeriod := d.Get("rotation_grace_period").(string); gracePeriod != "" {
+			duration, _ := time.ParseDuration(gracePeriod)
+			expiresAt = time.Now().Add(duration).UTC().Format(time.RFC3339)
+		}
+		d.Set("previous_expires_at", expiresAt)
+		d.Set("credentials", credentials)
+		d.SetId(guid)
+		return nil
+	}
+
+	if d.HasChange("previous_id") && d.Get("previous_id").(string) == "" {
+		oldPrevious, _ := d.GetChange("previous_id")
+		if err := deleteServiceKey(session, oldPrevious.(string), d.Timeout(schema.TimeoutUpdate)); err != nil && !IsErrNotFound(err) {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceServiceKeyRead(ctx, d, meta)
+}
+
 func resourceServiceKeyDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	if previousID := d.Get("previous_id").(string); previousID != "" {
+		if err := deleteServiceKey(session, previousID, d.Timeout(schema.TimeoutDelete)); err != nil && !IsErrNotFound(err) {
+			return diag.FromErr(err)
+		}
+	}
+	return diag.FromErr(deleteServiceKey(session, d.Id(), d.Timeout(schema.TimeoutDelete)))
+}
+
+func deleteServiceKey(session *managers.Session, guid string, timeout time.Duration) error {
 	// Note : When deleting credential bindings originated from user provided service instances,
 	// the delete operation does not require interactions with service brokers,
 	// therefore the API will respond synchronously to the delete request.
-	session := meta.(*managers.Session)
-	jobURL, _, err := session.ClientV3.DeleteServiceCredentialBinding(d.Id())
+	jobURL, _, err := session.ClientV3.DeleteServiceCredentialBinding(guid)
 
 	if err != nil {
-		return diag.FromErr(err)
+		return err
 	}
 
 	if jobURL == "" {
-		log.Printf("[INFO] Deleted service credential binding %s for User-Provided service instance, finishing without polling", d.Id())
-		return diag.FromErr(err)
+		log.Printf("[INFO] Deleted service credential binding %s for User-Provided service instance, finishing without polling", guid)
+		return nil
 	}
 
 	// Polling when deleting service credential binding for a managed service instance
-	err = common.PollingWithTimeout(func() (bool, error) {
+	return common.PollingWithTimeout(func() (bool, error) {
 		job, _, err := session.ClientV3.GetJob(jobURL)
 		if err != nil {
 			return true, err
@@ -245,6 +444,5 @@ func resourceServiceKeyDelete(ctx context.Context, d *schema.ResourceData, meta
 		}
 		// Last operation initial or inprogress or job not completed, continue polling
 		return false, nil
-	}, 5*time.Second, d.Timeout(schema.TimeoutDelete))
-	return diag.FromErr(err)
+	}, 5*time.Second, timeout)
 }
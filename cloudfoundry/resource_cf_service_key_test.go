@@ -36,6 +36,30 @@ resource "cloudfoundry_service_key" "test-service-instance-key" {
 }
 `
 
+const serviceKeyResourceRotation = `
+data "cloudfoundry_service" "test-service" {
+  name = "%s"
+}
+
+resource "cloudfoundry_service_instance" "test-service-instance" {
+	name = "test-service-instance-rotation"
+  space = "%s"
+  service_plan = "${data.cloudfoundry_service.test-service.service_plans["%s"]}"
+}
+
+resource "cloudfoundry_service_key" "test-service-instance-key" {
+	name = "test-service-instance-key-rotation"
+	service_instance = "${cloudfoundry_service_instance.test-service-instance.id}"
+	rotation_trigger = "%s"
+
+	timeouts {
+		create = "3m"
+		update = "5m"
+		delete = "2m"
+	}
+}
+`
+
 func TestAccResServiceKey_normal(t *testing.T) {
 
 	spaceId, _ := defaultTestSpace(t)
@@ -64,6 +88,68 @@ func TestAccResServiceKey_normal(t *testing.T) {
 		})
 }
 
+func TestAccResServiceKey_rotation(t *testing.T) {
+
+	spaceId, _ := defaultTestSpace(t)
+	serviceName1, _, servicePlan := getTestServiceBrokers(t)
+
+	ref := "cloudfoundry_service_key.test-service-instance-key"
+	var firstID string
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy: testAccCheckServiceKeyDestroyed(
+				"test-service-instance-key-rotation", ref),
+			Steps: []resource.TestStep{
+
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceKeyResourceRotation,
+						serviceName1, spaceId, servicePlan, "2024-01"),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServiceKeyExists(ref),
+						resource.TestCheckResourceAttr(ref, "previous_id", ""),
+						func(s *terraform.State) error {
+							firstID = s.RootModule().Resources[ref].Primary.ID
+							return nil
+						},
+					),
+				},
+
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceKeyResourceRotation,
+						serviceName1, spaceId, servicePlan, "2024-02"),
+					// previous key without grace period is planned for deletion on next apply
+					ExpectNonEmptyPlan: true,
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServiceKeyExists(ref),
+						func(s *terraform.State) error {
+							rs := s.RootModule().Resources[ref].Primary
+							if rs.ID == firstID {
+								return fmt.Errorf("service key has not been rotated")
+							}
+							if rs.Attributes["previous_id"] != firstID {
+								return fmt.Errorf("expected previous_id to be '%s' but was '%s'", firstID, rs.Attributes["previous_id"])
+							}
+							return nil
+						},
+					),
+				},
+
+				resource.TestStep{
+					Config: fmt.Sprintf(serviceKeyResourceRotation,
+						serviceName1, spaceId, servicePlan, "2024-02"),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServiceKeyExists(ref),
+						resource.TestCheckResourceAttr(ref, "previous_id", ""),
+						resource.TestCheckResourceAttr(ref, "previous_credentials.%", "0"),
+					),
+				},
+			},
+		})
+}
+
 func testAccCheckServiceKeyExists(resource string) resource.TestCheckFunc {
 
 	return func(s *terraform.State) error {
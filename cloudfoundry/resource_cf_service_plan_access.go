@@ -2,20 +2,35 @@ package cloudfoundry
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 )
 
+const (
+	servicePlanVisibilityPublic       = "public"
+	servicePlanVisibilityAdmin        = "admin"
+	servicePlanVisibilityOrganization = "organization"
+	servicePlanVisibilitySpace        = "space"
+
+	servicePlanVisibilityImportPrefix = "visibility/"
+)
+
 func resourceServicePlanAccess() *schema.Resource {
 	return &schema.Resource{
 		CreateContext: resourceServicePlanAccessCreate,
 		ReadContext:   resourceServicePlanAccessRead,
+		UpdateContext: resourceServicePlanAccessUpdate,
 		DeleteContext: resourceServicePlanAccessDelete,
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceServicePlanAccessImport,
 		},
+		CustomizeDiff: resourceServicePlanAccessCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"plan": &schema.Schema{
 				Type:     schema.TypeString,
@@ -26,18 +41,63 @@ func resourceServicePlanAccess() *schema.Resource {
 				Type:          schema.TypeString,
 				Optional:      true,
 				ForceNew:      true,
-				ConflictsWith: []string{"public"},
+				ConflictsWith: []string{"public", "visibility", "orgs"},
 			},
 			"public": &schema.Schema{
 				Type:          schema.TypeBool,
 				Optional:      true,
 				ForceNew:      true,
-				ConflictsWith: []string{"org"},
+				ConflictsWith: []string{"org", "visibility", "orgs"},
+			},
+			"visibility": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"org", "public"},
+				ValidateFunc: validation.StringInSlice([]string{
+					servicePlanVisibilityPublic,
+					servicePlanVisibilityAdmin,
+					servicePlanVisibilityOrganization,
+					servicePlanVisibilitySpace,
+				}, false),
+				Description: "Visibility of the plan managed through v3 api, one of public, admin, organization or space",
+			},
+			"orgs": &schema.Schema{
+				Type:          schema.TypeSet,
+				Optional:      true,
+				Elem:          &schema.Schema{Type: schema.TypeString},
+				Set:           schema.HashString,
+				ConflictsWith: []string{"org", "public"},
+				Description:   "Complete list of orgs having access to the plan when visibility is organization",
+			},
+			"space": &schema.Schema{
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Space having access to the plan when visibility is space",
 			},
 		},
 	}
 }
 
+func resourceServicePlanAccessCustomizeDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("visibility") || !d.NewValueKnown("orgs") {
+		return nil
+	}
+	// without visibility, access is managed through v2 api with public or org, the plan is reset to admin visibility
+	// by replacing the resource
+	if oldVisibility, newVisibility := d.GetChange("visibility"); d.Id() != "" && oldVisibility.(string) != "" && newVisibility.(string) == "" {
+		return d.ForceNew("visibility")
+	}
+	visibility := d.Get("visibility").(string)
+	orgs := d.Get("orgs").(*schema.Set)
+	if visibility == servicePlanVisibilityOrganization && orgs.Len() == 0 {
+		return fmt.Errorf("orgs must be set when visibility is %s", servicePlanVisibilityOrganization)
+	}
+	if visibility != servicePlanVisibilityOrganization && orgs.Len() > 0 {
+		return fmt.Errorf("orgs can only be set when visibility is %s", servicePlanVisibilityOrganization)
+	}
+	return nil
+}
+
 func resourceServicePlanAccessCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 
 	session := meta.(*managers.Session)
@@ -49,6 +109,14 @@ func resourceServicePlanAccessCreate(ctx context.Context, d *schema.ResourceData
 	public, hasPublic := d.GetOkExists("public")
 	org, hasOrg := d.GetOk("org")
 
+	if _, ok := d.GetOk("visibility"); ok {
+		if err := servicePlanVisibilityApply(ctx, d, session); err != nil {
+			return diag.FromErr(err)
+		}
+		d.SetId(plan)
+		return resourceServicePlanAccessRead(ctx, d, meta)
+	}
+
 	var id string
 	if hasOrg {
 		spV, _, err := session.ClientV2.CreateServicePlanVisibility(plan, org.(string))
@@ -77,6 +145,20 @@ func resourceServicePlanAccessRead(ctx context.Context, d *schema.ResourceData,
 
 	_, hasOrg := d.GetOk("org")
 
+	if _, ok := d.GetOk("visibility"); ok {
+		spV, err := session.ClientGo.ServicePlansVisibility.Get(ctx, d.Id())
+		if err != nil {
+			if IsErrNotFound(err) {
+				d.SetId("")
+				return nil
+			}
+			return diag.FromErr(err)
+		}
+		d.Set("plan", d.Id())
+		setServicePlanVisibility(d, spV)
+		return nil
+	}
+
 	if hasOrg {
 		spV, _, err := session.ClientV2.GetServicePlanVisibility(d.Id())
 		if err != nil {
@@ -104,9 +186,33 @@ func resourceServicePlanAccessRead(ctx context.Context, d *schema.ResourceData,
 	return nil
 }
 
+func resourceServicePlanAccessUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	if d.HasChanges("visibility", "orgs") {
+		if err := servicePlanVisibilityApply(ctx, d, session); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceServicePlanAccessRead(ctx, d, meta)
+}
+
 func resourceServicePlanAccessDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	session := meta.(*managers.Session)
 
+	if visibility, ok := d.GetOk("visibility"); ok {
+		// visibility of a space scoped plan can't be changed, other plans are only kept visible to admins
+		if visibility.(string) == servicePlanVisibilitySpace || visibility.(string) == servicePlanVisibilityAdmin {
+			return nil
+		}
+		_, err := session.ClientGo.ServicePlansVisibility.Update(ctx, d.Id(),
+			resource.NewServicePlanVisibilityUpdate(resource.ServicePlanVisibilityAdmin))
+		if err != nil && !IsErrNotFound(err) {
+			return diag.FromErr(err)
+		}
+		return nil
+	}
+
 	_, hasOrg := d.GetOk("org")
 	if !hasOrg {
 		return nil
@@ -114,3 +220,49 @@ func resourceServicePlanAccessDelete(ctx context.Context, d *schema.ResourceData
 	_, err := session.ClientV2.DeleteServicePlanVisibility(d.Id())
 	return diag.FromErr(err)
 }
+
+// servicePlanVisibilityApply sets visibility of the plan, org list replaces any org previously given access
+func servicePlanVisibilityApply(ctx context.Context, d *schema.ResourceData, session *managers.Session) error {
+	plan := d.Get("plan").(string)
+	visibility := d.Get("visibility").(string)
+
+	if visibility == servicePlanVisibilitySpace {
+		// space visibility is given by cloud controller to plans of space scoped brokers and can't be set
+		spV, err := session.ClientGo.ServicePlansVisibility.Get(ctx, plan)
+		if err != nil {
+			return err
+		}
+		if spV.Type != servicePlanVisibilitySpace {
+			return fmt.Errorf("visibility of plan '%s' is %s, visibility %s can only be used for plans of space scoped brokers",
+				plan, spV.Type, servicePlanVisibilitySpace)
+		}
+		return nil
+	}
+
+	spV := &resource.ServicePlanVisibility{
+		Type: visibility,
+	}
+	if visibility == servicePlanVisibilityOrganization {
+		for _, org := range d.Get("orgs").(*schema.Set).List() {
+			spV.Organizations = append(spV.Organizations, resource.ServicePlanVisibilityRelation{
+				GUID: org.(string),
+			})
+		}
+	}
+	_, err := session.ClientGo.ServicePlansVisibility.Update(ctx, plan, spV)
+	return err
+}
+
+func setServicePlanVisibility(d *schema.ResourceData, spV *resource.ServicePlanVisibility) {
+	d.Set("visibility", spV.Type)
+	orgs := make([]interface{}, 0, len(spV.Organizations))
+	for _, org := range spV.Organizations {
+		orgs = append(orgs, org.GUID)
+	}
+	d.Set("orgs", schema.NewSet(schema.HashString, orgs))
+	space := ""
+	if spV.Space != nil {
+		space = spV.Space.GUID
+	}
+	d.Set("space", space)
+}
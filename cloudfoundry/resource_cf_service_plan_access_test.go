@@ -1,6 +1,7 @@
 package cloudfoundry
 
 import (
+	"context"
 	"fmt"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
 	"regexp"
@@ -67,6 +68,35 @@ resource "cloudfoundry_service_plan_access" "test-access" {
 }
 `
 
+const saResourceVisibilityOrgs = `
+resource "cloudfoundry_service_broker" "test" {
+	name = "test"
+	url = "%s"
+	username = "%s"
+	password = "%s"
+}
+
+resource "cloudfoundry_service_plan_access" "test-access" {
+	plan = "${cloudfoundry_service_broker.test.service_plans["%s"]}"
+	visibility = "organization"
+	orgs = ["%s"]
+}
+`
+
+const saResourceVisibilityPublic = `
+resource "cloudfoundry_service_broker" "test" {
+	name = "test"
+	url = "%s"
+	username = "%s"
+	password = "%s"
+}
+
+resource "cloudfoundry_service_plan_access" "test-access" {
+	plan = "${cloudfoundry_service_broker.test.service_plans["%s"]}"
+	visibility = "public"
+}
+`
+
 func TestAccResServicePlanAccess_normal(t *testing.T) {
 
 	serviceBrokerURL, serviceBrokerUser, serviceBrokerPassword, serviceBrokerPlanPath := getTestBrokerCredentials(t)
@@ -130,6 +160,93 @@ func TestAccResServicePlanAccess_normal(t *testing.T) {
 		})
 }
 
+func TestAccResServicePlanAccess_visibility(t *testing.T) {
+
+	serviceBrokerURL, serviceBrokerUser, serviceBrokerPassword, serviceBrokerPlanPath := getTestBrokerCredentials(t)
+
+	// Ensure any test artifacts from a
+	// failed run are deleted if the exist
+	deleteServiceBroker("test")
+
+	orgID, _ := defaultTestOrg(t)
+	ref := "cloudfoundry_service_plan_access.test-access"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckServiceBrokerDestroyed("test"),
+			Steps: []resource.TestStep{
+				resource.TestStep{
+					Config: fmt.Sprintf(saResourceVisibilityOrgs,
+						serviceBrokerURL,
+						serviceBrokerUser,
+						serviceBrokerPassword,
+						serviceBrokerPlanPath,
+						orgID),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServicePlanVisibility(ref, "organization"),
+						resource.TestCheckResourceAttr(ref, "visibility", "organization"),
+						resource.TestCheckResourceAttr(ref, "orgs.#", "1"),
+						resource.TestCheckTypeSetElemAttr(ref, "orgs.*", orgID),
+					),
+				},
+				resource.TestStep{
+					ResourceName:      ref,
+					ImportState:       true,
+					ImportStateVerify: true,
+					ImportStateIdFunc: func(s *terraform.State) (string, error) {
+						return "visibility/" + s.RootModule().Resources[ref].Primary.ID, nil
+					},
+				},
+				resource.TestStep{
+					Config: fmt.Sprintf(saResourceVisibilityPublic,
+						serviceBrokerURL,
+						serviceBrokerUser,
+						serviceBrokerPassword,
+						serviceBrokerPlanPath),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServicePlanVisibility(ref, "public"),
+						resource.TestCheckResourceAttr(ref, "visibility", "public"),
+						resource.TestCheckResourceAttr(ref, "orgs.#", "0"),
+					),
+				},
+				resource.TestStep{
+					Config: fmt.Sprintf(saResourceUpdateTrue,
+						serviceBrokerURL,
+						serviceBrokerUser,
+						serviceBrokerPassword,
+						serviceBrokerPlanPath),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckServicePlan(ref),
+						resource.TestCheckResourceAttr(ref, "public", "true"),
+						resource.TestCheckResourceAttr(ref, "visibility", ""),
+					),
+				},
+			},
+		})
+}
+
+func TestServicePlanAccessVisibilityRemoved(t *testing.T) {
+	state := &terraform.InstanceState{
+		ID: "plan-guid",
+		Attributes: map[string]string{
+			"plan":       "plan-guid",
+			"visibility": "public",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"plan": "plan-guid",
+	})
+	diff, err := resourceServicePlanAccess().Diff(context.Background(), state, config, nil)
+	if err != nil {
+		t.Fatalf("bad: %#v", err)
+	}
+	if diff == nil || !diff.RequiresNew() {
+		t.Fatalf("bad: removing visibility must replace the resource, got %#v", diff)
+	}
+}
+
 func TestAccResServicePlanAccess_error(t *testing.T) {
 
 	serviceBrokerURL, serviceBrokerUser, serviceBrokerPassword, serviceBrokerPlanPath := getTestBrokerCredentials(t)
@@ -217,6 +334,25 @@ func testAccCheckServicePlan(resource string) resource.TestCheckFunc {
 	}
 }
 
+func testAccCheckServicePlanVisibility(resource string, visibility string) resource.TestCheckFunc {
+	return func(s *terraform.State) (err error) {
+		session := testAccProvider.Meta().(*managers.Session)
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("service access resource '%s' not found in terraform state", resource)
+		}
+
+		spV, err := session.ClientGo.ServicePlansVisibility.Get(context.Background(), rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		if spV.Type != visibility {
+			return fmt.Errorf("expected visibility '%s' for plan '%s', got '%s'", visibility, rs.Primary.ID, spV.Type)
+		}
+		return nil
+	}
+}
+
 func testAccCheckServicePlanAccessDestroyed(servicePlanAccessGUID string) resource.TestCheckFunc {
 
 	return func(s *terraform.State) error {
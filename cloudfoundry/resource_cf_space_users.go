@@ -32,12 +32,15 @@ func resourceSpaceUsers() *schema.Resource {
 			StateContext: ImportReadContext(resourceSpaceUsersRead),
 		},
 
+		CustomizeDiff: rolesByUsernameCustomizeDiff,
+
 		Schema: map[string]*schema.Schema{
 			"space": {
 				Type:     schema.TypeString,
 				ForceNew: true,
 				Required: true,
 			},
+			"users_by_username": usersByUsernameSchema(spaceRoleTypes),
 			"force": {
 				Type:     schema.TypeBool,
 				Optional: true,
@@ -103,6 +106,10 @@ func resourceSpaceUsersRead(_ context.Context, d *schema.ResourceData, meta inte
 		_ = d.Set("force", false)
 	}
 	session := meta.(*managers.Session)
+	byUsernameGUIDs, err := readRolesByUsername(session, d, roleScopeSpace, d.Get("space").(string), spaceRoleTypes)
+	if err != nil {
+		return diag.FromErr(err)
+	}
 	for t, r := range typeToSpaceRoleMap {
 		users, _, err := session.ClientV2.GetSpaceUsersByRole(r, d.Get("space").(string))
 		if err != nil {
@@ -115,6 +122,8 @@ func resourceSpaceUsersRead(_ context.Context, d *schema.ResourceData, meta inte
 			})
 			_ = d.Set(t, schema.NewSet(resourceStringHash, finalUsers))
 		} else {
+			// users given by username are managed in users_by_username
+			users = excludeUsersByGUID(users, byUsernameGUIDs[t])
 			usersByUsername := intersectSlices(tfUsers, users, func(source, item interface{}) bool {
 				return strings.EqualFold(source.(string), item.(ccv2.User).Username)
 			})
@@ -170,7 +179,8 @@ func resourceSpaceUsersUpdate(_ context.Context, d *schema.ResourceData, meta in
 			}
 		}
 	}
-	return nil
+	err = updateRolesByUsername(session, d, roleScopeSpace, spaceId, spaceRoleTypes, space.OrganizationGUID)
+	return diag.FromErr(err)
 }
 
 func updateSpaceUserByRole(session *managers.Session, role constant.UserRole, guid string, guidOrUsername string, byUsername bool) error {
@@ -262,5 +272,6 @@ func resourceSpaceUsersDelete(_ context.Context, d *schema.ResourceData, meta in
 			}
 		}
 	}
-	return nil
+	err := deleteRolesByUsername(session, d, roleScopeSpace, spaceId, spaceRoleTypes)
+	return diag.FromErr(err)
 }
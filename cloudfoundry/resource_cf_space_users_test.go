@@ -4,6 +4,7 @@ import (
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
 	"fmt"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
@@ -81,6 +82,17 @@ resource "cloudfoundry_space_users" "space_users1" {
 }
 `
 
+const spaceUsersResourceByUsername = `
+resource "cloudfoundry_space_users" "space_users1" {
+	space = "%s"
+	users_by_username {
+		username = "%s"
+		origin = "uaa"
+		roles = [ %s ]
+	}
+}
+`
+
 func TestAccResSpaceUsers_normal(t *testing.T) {
 	ref := "cloudfoundry_space_users.space_users1"
 	spaceId, _ := defaultTestSpace(t)
@@ -173,6 +185,53 @@ func TestAccResSpaceUsers_force(t *testing.T) {
 		})
 }
 
+func TestAccResSpaceUsers_byUsername(t *testing.T) {
+	ref := "cloudfoundry_space_users.space_users1"
+	spaceId, _ := defaultTestSpace(t)
+	usersMap := make(map[string][]ccv2.User)
+
+	// user only exists in uaa as a federated user before first login, org membership is given by the resource
+	sessions := testSession()
+	user, err := sessions.ClientUAA.CreateUser("test-acc-space-by-username@acme.com", "paasw0rd", "uaa")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		_ = sessions.ClientUAA.DeleteUser(user.ID)
+	}()
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			Steps: []resource.TestStep{
+				{
+					Config:      fmt.Sprintf(spaceUsersResourceByUsername, spaceId, "test-acc-unknown@acme.com", `"developers"`),
+					PlanOnly:    true,
+					ExpectError: regexp.MustCompile(`not found: test-acc-unknown@acme.com \(origin uaa\)`),
+				},
+				{
+					Config: fmt.Sprintf(spaceUsersResourceByUsername, spaceId, "test-acc-space-by-username@acme.com", `"developers", "auditors"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckSpaceUsersExists(ref, &usersMap),
+						testAccCheckMapUserInside("test-acc-space-by-username@acme.com", "developers", &usersMap),
+						testAccCheckMapUserInside("test-acc-space-by-username@acme.com", "auditors", &usersMap),
+						resource.TestCheckResourceAttr(ref, "users_by_username.#", "1"),
+						resource.TestCheckResourceAttr(ref, "users_by_username.0.roles.#", "2"),
+					),
+				},
+				{
+					Config: fmt.Sprintf(spaceUsersResourceByUsername, spaceId, "test-acc-space-by-username@acme.com", `"auditors"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckSpaceUsersExists(ref, &usersMap),
+						testAccCheckMapUserInside("test-acc-space-by-username@acme.com", "auditors", &usersMap),
+						resource.TestCheckResourceAttr(ref, "users_by_username.0.roles.#", "1"),
+					),
+				},
+			},
+		})
+}
+
 func testAccCheckSpaceUsersExists(resource string, users *map[string][]ccv2.User) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		session := testAccProvider.Meta().(*managers.Session)
@@ -0,0 +1,270 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+// uaaClient is an oauth client as given by uaa /oauth/clients api
+type uaaClient struct {
+	ClientID             string   `json:"client_id"`
+	ClientSecret         string   `json:"client_secret,omitempty"`
+	Name                 string   `json:"name,omitempty"`
+	AuthorizedGrantTypes []string `json:"authorized_grant_types"`
+	Scope                []string `json:"scope"`
+	Authorities          []string `json:"authorities"`
+	ResourceIDs          []string `json:"resource_ids,omitempty"`
+	RedirectURI          []string `json:"redirect_uri,omitempty"`
+	AccessTokenValidity  int      `json:"access_token_validity,omitempty"`
+	RefreshTokenValidity int      `json:"refresh_token_validity,omitempty"`
+}
+
+// uaaClientSecretChange is the body of a secret change, changeMode ADD keeps the current secret active
+// alongside the new one and DELETE removes the oldest of two secrets
+type uaaClientSecretChange struct {
+	ClientID   string `json:"clientId"`
+	Secret     string `json:"secret,omitempty"`
+	ChangeMode string `json:"changeMode,omitempty"`
+}
+
+const (
+	uaaClientSecretChangeAdd    = "ADD"
+	uaaClientSecretChangeDelete = "DELETE"
+
+	// uaaClientNoScope is the scope given by uaa to clients without scope or authorities
+	uaaClientNoScope = "uaa.none"
+)
+
+func resourceUAAClient() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceUAAClientCreate,
+		ReadContext:   resourceUAAClientRead,
+		UpdateContext: resourceUAAClientUpdate,
+		DeleteContext: resourceUAAClientDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: ImportReadContext(resourceUAAClientRead),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"client_id": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			"client_secret": &schema.Schema{
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			"keep_previous_secret": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Keep previous secret valid alongside the new one when client_secret changes, previous secret is revoked when set back to false",
+			},
+			"has_previous_secret": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"authorized_grant_types": &schema.Schema{
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"scope": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"authorities": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"resource_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"redirect_uri": &schema.Schema{
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Set:      schema.HashString,
+			},
+			"access_token_validity": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Validity of access tokens in seconds, uaa default is used when not set",
+			},
+			"refresh_token_validity": &schema.Schema{
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Validity of refresh tokens in seconds, uaa default is used when not set",
+			},
+		},
+	}
+}
+
+func resourceUAAClientCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	client := resourceDataToUAAClient(d)
+	client.ClientSecret = d.Get("client_secret").(string)
+	_, err := uaaRawJSONRequest(session, http.MethodPost, "/oauth/clients", client, nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(client.ClientID)
+	d.Set("has_previous_secret", false)
+	return resourceUAAClientRead(ctx, d, meta)
+}
+
+func resourceUAAClientRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	client, err := getUAAClient(session, d.Id())
+	if err != nil {
+		if IsErrNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	client.Scope = uaaClientScopes(d, "scope", client.Scope)
+	client.Authorities = uaaClientScopes(d, "authorities", client.Authorities)
+	uaaClientToResourceData(d, client)
+	return nil
+}
+
+func resourceUAAClientUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	clientPath := "/oauth/clients/" + url.PathEscape(d.Id())
+
+	if d.HasChanges("name", "authorized_grant_types", "scope", "authorities", "resource_ids",
+		"redirect_uri", "access_token_validity", "refresh_token_validity") {
+		_, err := uaaRawJSONRequest(session, http.MethodPut, clientPath, resourceDataToUAAClient(d), nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	keepPrevious := d.Get("keep_previous_secret").(bool)
+	hasPrevious := d.Get("has_previous_secret").(bool)
+
+	// uaa keeps at most two secrets, the oldest one must be revoked before adding a new one
+	if hasPrevious && (!keepPrevious || d.HasChange("client_secret")) {
+		_, err := uaaRawJSONRequest(session, http.MethodPut, clientPath+"/secret", uaaClientSecretChange{
+			ClientID:   d.Id(),
+			ChangeMode: uaaClientSecretChangeDelete,
+		}, nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("has_previous_secret", false)
+	}
+
+	if d.HasChange("client_secret") {
+		secretChange := uaaClientSecretChange{
+			ClientID: d.Id(),
+			Secret:   d.Get("client_secret").(string),
+		}
+		if keepPrevious {
+			secretChange.ChangeMode = uaaClientSecretChangeAdd
+		}
+		_, err := uaaRawJSONRequest(session, http.MethodPut, clientPath+"/secret", secretChange, nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		d.Set("has_previous_secret", keepPrevious)
+	}
+	return resourceUAAClientRead(ctx, d, meta)
+}
+
+func resourceUAAClientDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	_, err := uaaRawJSONRequest(session, http.MethodDelete, "/oauth/clients/"+url.PathEscape(d.Id()), nil, nil)
+	if err != nil && !IsErrNotFound(err) {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func getUAAClient(session *managers.Session, clientID string) (uaaClient, error) {
+	var client uaaClient
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/oauth/clients/"+url.PathEscape(clientID), nil, &client)
+	if err != nil {
+		return uaaClient{}, err
+	}
+	if client.ClientID == "" {
+		return uaaClient{}, fmt.Errorf("uaa client '%s' has not been found", clientID)
+	}
+	return client, nil
+}
+
+func resourceDataToUAAClient(d *schema.ResourceData) uaaClient {
+	return uaaClient{
+		ClientID:             d.Get("client_id").(string),
+		Name:                 d.Get("name").(string),
+		AuthorizedGrantTypes: uaaStringSet(d, "authorized_grant_types"),
+		Scope:                uaaStringSet(d, "scope"),
+		Authorities:          uaaStringSet(d, "authorities"),
+		ResourceIDs:          uaaStringSet(d, "resource_ids"),
+		RedirectURI:          uaaStringSet(d, "redirect_uri"),
+		AccessTokenValidity:  d.Get("access_token_validity").(int),
+		RefreshTokenValidity: d.Get("refresh_token_validity").(int),
+	}
+}
+
+func uaaClientToResourceData(d *schema.ResourceData, client uaaClient) {
+	d.Set("client_id", client.ClientID)
+	d.Set("name", client.Name)
+	d.Set("authorized_grant_types", client.AuthorizedGrantTypes)
+	d.Set("scope", client.Scope)
+	d.Set("authorities", client.Authorities)
+	d.Set("resource_ids", client.ResourceIDs)
+	d.Set("redirect_uri", client.RedirectURI)
+	d.Set("access_token_validity", client.AccessTokenValidity)
+	d.Set("refresh_token_validity", client.RefreshTokenValidity)
+}
+
+// uaaClientScopes gives scopes read from uaa, uaa.none set by uaa when no scope is given is read as no scope
+// unless it is asked explicitly
+func uaaClientScopes(d *schema.ResourceData, key string, scopes []string) []string {
+	if len(scopes) == 1 && scopes[0] == uaaClientNoScope && !d.Get(key).(*schema.Set).Contains(uaaClientNoScope) {
+		return []string{}
+	}
+	return scopes
+}
+
+func uaaStringSet(d *schema.ResourceData, key string) []string {
+	values := make([]string, 0)
+	for _, v := range d.Get(key).(*schema.Set).List() {
+		values = append(values, v.(string))
+	}
+	return values
+}
@@ -0,0 +1,152 @@
+package cloudfoundry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const uaaClientResource = `
+
+resource "cloudfoundry_uaa_client" "ci" {
+	client_id = "tf-acc-ci"
+	client_secret = "%s"
+	keep_previous_secret = %t
+	authorized_grant_types = [ "client_credentials" ]
+	authorities = [ %s ]
+	access_token_validity = 600
+}
+`
+
+const uaaClientDataResource = `
+
+resource "cloudfoundry_uaa_client" "ci" {
+	client_id = "tf-acc-ci"
+	client_secret = "secret1"
+	authorized_grant_types = [ "client_credentials" ]
+	authorities = [ "cloud_controller.read" ]
+}
+
+data "cloudfoundry_uaa_client" "ci" {
+	client_id = cloudfoundry_uaa_client.ci.client_id
+}
+`
+
+func TestAccResUAAClient_normal(t *testing.T) {
+
+	ref := "cloudfoundry_uaa_client.ci"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckUAAClientDestroy("tf-acc-ci"),
+			Steps: []resource.TestStep{
+
+				{
+					Config: fmt.Sprintf(uaaClientResource, "secret1", false, `"cloud_controller.read"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAClientExists(ref),
+						resource.TestCheckResourceAttr(ref, "client_id", "tf-acc-ci"),
+						resource.TestCheckResourceAttr(ref, "authorized_grant_types.#", "1"),
+						resource.TestCheckResourceAttr(ref, "authorities.#", "1"),
+						resource.TestCheckResourceAttr(ref, "access_token_validity", "600"),
+						resource.TestCheckResourceAttr(ref, "has_previous_secret", "false"),
+					),
+				},
+
+				{
+					Config: fmt.Sprintf(uaaClientResource, "secret2", true, `"cloud_controller.read", "cloud_controller.write"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAClientExists(ref),
+						resource.TestCheckResourceAttr(ref, "authorities.#", "2"),
+						resource.TestCheckResourceAttr(ref, "has_previous_secret", "true"),
+					),
+				},
+
+				{
+					Config: fmt.Sprintf(uaaClientResource, "secret2", false, `"cloud_controller.read", "cloud_controller.write"`),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAClientExists(ref),
+						resource.TestCheckResourceAttr(ref, "has_previous_secret", "false"),
+					),
+				},
+
+				{
+					Config: fmt.Sprintf(uaaClientResource, "secret2", false, ``),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAClientExists(ref),
+						resource.TestCheckResourceAttr(ref, "authorities.#", "0"),
+					),
+				},
+
+				{
+					ResourceName:            ref,
+					ImportState:             true,
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"client_secret", "keep_previous_secret", "has_previous_secret"},
+				},
+			},
+		})
+}
+
+func TestAccDataSourceUAAClient_normal(t *testing.T) {
+
+	ref := "data.cloudfoundry_uaa_client.ci"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckUAAClientDestroy("tf-acc-ci"),
+			Steps: []resource.TestStep{
+
+				{
+					Config: uaaClientDataResource,
+					Check: resource.ComposeTestCheckFunc(
+						resource.TestCheckResourceAttr(ref, "id", "tf-acc-ci"),
+						resource.TestCheckResourceAttr(ref, "authorized_grant_types.#", "1"),
+						resource.TestCheckTypeSetElemAttr(ref, "authorities.*", "cloud_controller.read"),
+					),
+				},
+			},
+		})
+}
+
+func testAccCheckUAAClientExists(resource string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		session := testAccProvider.Meta().(*managers.Session)
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("uaa client '%s' not found in terraform state", resource)
+		}
+		client, err := getUAAClient(session, rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		return assertSame(client.ClientID, rs.Primary.Attributes["client_id"])
+	}
+}
+
+func testAccCheckUAAClientDestroy(clientID string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		session := testAccProvider.Meta().(*managers.Session)
+		_, err := getUAAClient(session, clientID)
+		if err == nil {
+			return fmt.Errorf("uaa client '%s' still exists", clientID)
+		}
+		if !IsErrNotFound(err) {
+			return err
+		}
+		return nil
+	}
+}
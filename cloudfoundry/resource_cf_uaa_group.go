@@ -0,0 +1,214 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+// uaaGroup is a scim group as given by uaa /Groups api
+type uaaGroup struct {
+	ID          string `json:"id,omitempty"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+	Meta        struct {
+		Version int `json:"version"`
+	} `json:"meta"`
+}
+
+// uaaExternalGroup maps a group of an external identity provider (ldap, saml...) to an uaa group
+type uaaExternalGroup struct {
+	GroupID       string `json:"groupId"`
+	ExternalGroup string `json:"externalGroup"`
+	Origin        string `json:"origin"`
+}
+
+func resourceUAAGroup() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceUAAGroupCreate,
+		ReadContext:   resourceUAAGroupRead,
+		UpdateContext: resourceUAAGroupUpdate,
+		DeleteContext: resourceUAAGroupDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: ImportReadContext(resourceUAAGroupRead),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"display_name": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringIsNotEmpty,
+				Description:  "Name of the group, which is the scope given to its members (e.g. network.write)",
+			},
+			"description": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"external_groups": &schema.Schema{
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Groups of external identity providers whose members are given membership of this group",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"external_group": &schema.Schema{
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringIsNotEmpty,
+						},
+						"origin": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "ldap",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceUAAGroupCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	var group uaaGroup
+	_, err := uaaRawJSONRequest(session, http.MethodPost, "/Groups", map[string]interface{}{
+		"displayName": d.Get("display_name").(string),
+		"description": d.Get("description").(string),
+	}, &group)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(group.ID)
+
+	for _, m := range d.Get("external_groups").(*schema.Set).List() {
+		err := addUAAExternalGroup(session, group.ID, m.(map[string]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceUAAGroupRead(ctx, d, meta)
+}
+
+func resourceUAAGroupRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	group, err := getUAAGroup(session, d.Id())
+	if err != nil {
+		if IsErrNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	d.Set("display_name", group.DisplayName)
+	d.Set("description", group.Description)
+
+	externalGroups, err := getUAAExternalGroups(session, d.Id())
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	tfExternalGroups := make([]interface{}, 0, len(externalGroups))
+	for _, e := range externalGroups {
+		tfExternalGroups = append(tfExternalGroups, map[string]interface{}{
+			"external_group": e.ExternalGroup,
+			"origin":         e.Origin,
+		})
+	}
+	d.Set("external_groups", tfExternalGroups)
+	return nil
+}
+
+func resourceUAAGroupUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+
+	if d.HasChanges("display_name", "description") {
+		group, err := getUAAGroup(session, d.Id())
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		// patch keeps group members unlike put which replaces them
+		_, err = uaaRawJSONRequestWithHeader(session, http.MethodPatch, "/Groups/"+url.PathEscape(d.Id()),
+			http.Header{"If-Match": []string{fmt.Sprintf("%d", group.Meta.Version)}},
+			map[string]interface{}{
+				"displayName": d.Get("display_name").(string),
+				"description": d.Get("description").(string),
+			}, nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	if d.HasChange("external_groups") {
+		old, new := d.GetChange("external_groups")
+		for _, m := range old.(*schema.Set).Difference(new.(*schema.Set)).List() {
+			err := deleteUAAExternalGroup(session, d.Id(), m.(map[string]interface{}))
+			if err != nil && !IsErrNotFound(err) {
+				return diag.FromErr(err)
+			}
+		}
+		for _, m := range new.(*schema.Set).Difference(old.(*schema.Set)).List() {
+			err := addUAAExternalGroup(session, d.Id(), m.(map[string]interface{}))
+			if err != nil {
+				return diag.FromErr(err)
+			}
+		}
+	}
+	return resourceUAAGroupRead(ctx, d, meta)
+}
+
+func resourceUAAGroupDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	// external group mappings are deleted by uaa with the group
+	_, err := uaaRawJSONRequest(session, http.MethodDelete, "/Groups/"+url.PathEscape(d.Id()), nil, nil)
+	if err != nil && !IsErrNotFound(err) {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func getUAAGroup(session *managers.Session, groupID string) (uaaGroup, error) {
+	var group uaaGroup
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/Groups/"+url.PathEscape(groupID), nil, &group)
+	return group, err
+}
+
+func getUAAExternalGroups(session *managers.Session, groupID string) ([]uaaExternalGroup, error) {
+	query := url.Values{
+		"filter": []string{fmt.Sprintf(`groupId eq "%s"`, groupID)},
+	}
+	var externalGroups struct {
+		Resources []uaaExternalGroup `json:"resources"`
+	}
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/Groups/External?"+query.Encode(), nil, &externalGroups)
+	if err != nil {
+		return nil, err
+	}
+	return externalGroups.Resources, nil
+}
+
+func addUAAExternalGroup(session *managers.Session, groupID string, mapping map[string]interface{}) error {
+	_, err := uaaRawJSONRequest(session, http.MethodPost, "/Groups/External", uaaExternalGroup{
+		GroupID:       groupID,
+		ExternalGroup: mapping["external_group"].(string),
+		Origin:        mapping["origin"].(string),
+	}, nil)
+	return err
+}
+
+func deleteUAAExternalGroup(session *managers.Session, groupID string, mapping map[string]interface{}) error {
+	path := strings.Join([]string{
+		"/Groups/External/groupId", url.PathEscape(groupID),
+		"externalGroup", url.PathEscape(mapping["external_group"].(string)),
+		"origin", url.PathEscape(mapping["origin"].(string)),
+	}, "/")
+	_, err := uaaRawJSONRequest(session, http.MethodDelete, path, nil, nil)
+	return err
+}
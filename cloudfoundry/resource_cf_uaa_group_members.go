@@ -0,0 +1,315 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+const (
+	uaaGroupMemberTypeUser  = "USER"
+	uaaGroupMemberTypeGroup = "GROUP"
+)
+
+// uaaGroupMember is a member of a scim group, value is the id of the user or of the nested group
+type uaaGroupMember struct {
+	Origin string `json:"origin"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+}
+
+func resourceUAAGroupMembers() *schema.Resource {
+	return &schema.Resource{
+		CreateContext: resourceUAAGroupMembersCreate,
+		ReadContext:   resourceUAAGroupMembersRead,
+		UpdateContext: resourceUAAGroupMembersUpdate,
+		DeleteContext: resourceUAAGroupMembersDelete,
+
+		Importer: &schema.ResourceImporter{
+			StateContext: ImportReadContext(resourceUAAGroupMembersRead),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"group_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"force": &schema.Schema{
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Members of the group not given in this resource are removed when set to true",
+			},
+			"users": &schema.Schema{
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Set:         schema.HashString,
+				Description: "IDs of the users member of the group",
+			},
+			"usernames": &schema.Schema{
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Description: "Users member of the group given by their username and origin",
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"username": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"origin": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+							Default:  "uaa",
+						},
+					},
+				},
+			},
+			"groups": &schema.Schema{
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Set:         schema.HashString,
+				Description: "IDs of the groups nested in the group, their members are given membership of the group",
+			},
+		},
+	}
+}
+
+func resourceUAAGroupMembersCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	d.SetId(id)
+	return resourceUAAGroupMembersUpdate(ctx, d, meta)
+}
+
+func resourceUAAGroupMembersRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	if IsImportState(d) {
+		d.Set("group_id", d.Id())
+	}
+	session := meta.(*managers.Session)
+
+	members, err := getUAAGroupMembers(session, d.Get("group_id").(string))
+	if err != nil {
+		if IsErrNotFound(err) {
+			d.SetId("")
+			return nil
+		}
+		return diag.FromErr(err)
+	}
+	current := make(map[string]uaaGroupMember)
+	for _, m := range members {
+		current[m.Value] = m
+	}
+
+	// users given by username are kept as is when they are still members
+	byUsername := make(map[string]bool)
+	usernames := make([]interface{}, 0)
+	for _, u := range d.Get("usernames").(*schema.Set).List() {
+		userID, err := uaaUserIDByUsername(session, u.(map[string]interface{}))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		if _, ok := current[userID]; ok && userID != "" {
+			byUsername[userID] = true
+			usernames = append(usernames, u)
+		}
+	}
+
+	all := d.Get("force").(bool) || IsImportState(d)
+	tfUsers := d.Get("users").(*schema.Set)
+	tfGroups := d.Get("groups").(*schema.Set)
+	users := make([]interface{}, 0)
+	groups := make([]interface{}, 0)
+	for _, m := range members {
+		if byUsername[m.Value] {
+			continue
+		}
+		if m.Type == uaaGroupMemberTypeGroup {
+			if all || tfGroups.Contains(m.Value) {
+				groups = append(groups, m.Value)
+			}
+			continue
+		}
+		if all || tfUsers.Contains(m.Value) {
+			users = append(users, m.Value)
+		}
+	}
+	d.Set("users", schema.NewSet(schema.HashString, users))
+	d.Set("groups", schema.NewSet(schema.HashString, groups))
+	d.Set("usernames", usernames)
+	return nil
+}
+
+func resourceUAAGroupMembersUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	groupID := d.Get("group_id").(string)
+
+	oldUsers, newUsers := d.GetChange("users")
+	oldUsernames, newUsernames := d.GetChange("usernames")
+	oldGroups, newGroups := d.GetChange("groups")
+
+	desired, err := uaaGroupMembersFromSets(session, newUsers.(*schema.Set), newUsernames.(*schema.Set), newGroups.(*schema.Set), true)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	previous, err := uaaGroupMembersFromSets(session, oldUsers.(*schema.Set), oldUsernames.(*schema.Set), oldGroups.(*schema.Set), false)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	members, err := getUAAGroupMembers(session, groupID)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	current := make(map[string]uaaGroupMember)
+	for _, m := range members {
+		current[m.Value] = m
+	}
+
+	// only members previously managed by this resource are removed unless force is set
+	removable := previous
+	if d.Get("force").(bool) {
+		removable = current
+	}
+	for memberID := range removable {
+		if _, ok := desired[memberID]; ok {
+			continue
+		}
+		if _, ok := current[memberID]; !ok {
+			continue
+		}
+		err := deleteUAAGroupMember(session, groupID, memberID)
+		if err != nil && !IsErrNotFound(err) {
+			return diag.FromErr(err)
+		}
+	}
+
+	for memberID, member := range desired {
+		if _, ok := current[memberID]; ok {
+			continue
+		}
+		if member.Origin == "" {
+			member.Origin, err = uaaUserOrigin(session, memberID)
+			if err != nil {
+				return diag.FromErr(err)
+			}
+		}
+		_, err := uaaRawJSONRequest(session, http.MethodPost, "/Groups/"+url.PathEscape(groupID)+"/members", member, nil)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	return resourceUAAGroupMembersRead(ctx, d, meta)
+}
+
+func resourceUAAGroupMembersDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	session := meta.(*managers.Session)
+	groupID := d.Get("group_id").(string)
+
+	members, err := uaaGroupMembersFromSets(session,
+		d.Get("users").(*schema.Set), d.Get("usernames").(*schema.Set), d.Get("groups").(*schema.Set), false)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	for memberID := range members {
+		err := deleteUAAGroupMember(session, groupID, memberID)
+		if err != nil && !IsErrNotFound(err) {
+			return diag.FromErr(err)
+		}
+	}
+	return nil
+}
+
+// uaaGroupMembersFromSets resolves members given in the resource by their id, users given by username
+// which can't be found are an error when mustExist is set and ignored otherwise
+func uaaGroupMembersFromSets(session *managers.Session, users, usernames, groups *schema.Set, mustExist bool) (map[string]uaaGroupMember, error) {
+	members := make(map[string]uaaGroupMember)
+	for _, userID := range users.List() {
+		// origin is only needed when adding the user and retrieved at this time
+		members[userID.(string)] = uaaGroupMember{
+			Type:  uaaGroupMemberTypeUser,
+			Value: userID.(string),
+		}
+	}
+	for _, u := range usernames.List() {
+		user := u.(map[string]interface{})
+		userID, err := uaaUserIDByUsername(session, user)
+		if err != nil {
+			return nil, err
+		}
+		if userID == "" {
+			if mustExist {
+				return nil, fmt.Errorf("user '%s' with origin '%s' has not been found in uaa", user["username"], user["origin"])
+			}
+			continue
+		}
+		members[userID] = uaaGroupMember{
+			Origin: user["origin"].(string),
+			Type:   uaaGroupMemberTypeUser,
+			Value:  userID,
+		}
+	}
+	for _, groupID := range groups.List() {
+		members[groupID.(string)] = uaaGroupMember{
+			Origin: "uaa",
+			Type:   uaaGroupMemberTypeGroup,
+			Value:  groupID.(string),
+		}
+	}
+	return members, nil
+}
+
+func getUAAGroupMembers(session *managers.Session, groupID string) ([]uaaGroupMember, error) {
+	members := make([]uaaGroupMember, 0)
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/Groups/"+url.PathEscape(groupID)+"/members", nil, &members)
+	return members, err
+}
+
+func deleteUAAGroupMember(session *managers.Session, groupID, memberID string) error {
+	_, err := uaaRawJSONRequest(session, http.MethodDelete,
+		"/Groups/"+url.PathEscape(groupID)+"/members/"+url.PathEscape(memberID), nil, nil)
+	return err
+}
+
+// uaaUserIDByUsername returns id of the user with the given username and origin, empty if user does not exist
+func uaaUserIDByUsername(session *managers.Session, user map[string]interface{}) (string, error) {
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, `"`, `\"`)
+	}
+	query := url.Values{
+		"attributes": []string{"id"},
+		"filter": []string{fmt.Sprintf(`userName eq "%s" and origin eq "%s"`,
+			escape(user["username"].(string)), escape(user["origin"].(string)))},
+	}
+	var users struct {
+		Resources []struct {
+			ID string `json:"id"`
+		} `json:"resources"`
+	}
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/Users?"+query.Encode(), nil, &users)
+	if err != nil {
+		return "", err
+	}
+	if len(users.Resources) == 0 {
+		return "", nil
+	}
+	return users.Resources[0].ID, nil
+}
+
+func uaaUserOrigin(session *managers.Session, userID string) (string, error) {
+	var user struct {
+		Origin string `json:"origin"`
+	}
+	_, err := uaaRawJSONRequest(session, http.MethodGet, "/Users/"+url.PathEscape(userID), nil, &user)
+	return user.Origin, err
+}
@@ -0,0 +1,127 @@
+package cloudfoundry
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+const uaaGroupResource = `
+
+resource "cloudfoundry_uaa_group" "custom" {
+	display_name = "tf-acc.%s"
+	description = "%s"
+
+	external_groups {
+		external_group = "cn=developers,ou=groups,dc=acme,dc=com"
+		origin = "ldap"
+	}
+}
+
+resource "cloudfoundry_uaa_group" "nested" {
+	display_name = "tf-acc.nested"
+}
+
+resource "cloudfoundry_user" "member" {
+	name = "tf-acc-group-member"
+	password = "qwerty"
+}
+
+resource "cloudfoundry_uaa_group_members" "custom" {
+	group_id = cloudfoundry_uaa_group.custom.id
+	force = true
+	usernames {
+		username = cloudfoundry_user.member.name
+	}
+	groups = [ cloudfoundry_uaa_group.nested.id ]
+}
+`
+
+func TestAccResUAAGroup_normal(t *testing.T) {
+
+	ref := "cloudfoundry_uaa_group.custom"
+	refMembers := "cloudfoundry_uaa_group_members.custom"
+
+	resource.Test(t,
+		resource.TestCase{
+			PreCheck:          func() { testAccPreCheck(t) },
+			ProviderFactories: testAccProvidersFactories,
+			CheckDestroy:      testAccCheckUAAGroupDestroy(),
+			Steps: []resource.TestStep{
+
+				{
+					Config: fmt.Sprintf(uaaGroupResource, "read", "custom read scope"),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAGroupExists(ref),
+						resource.TestCheckResourceAttr(ref, "display_name", "tf-acc.read"),
+						resource.TestCheckResourceAttr(ref, "description", "custom read scope"),
+						resource.TestCheckResourceAttr(ref, "external_groups.#", "1"),
+						resource.TestCheckResourceAttr(refMembers, "usernames.#", "1"),
+						resource.TestCheckResourceAttr(refMembers, "users.#", "0"),
+						resource.TestCheckResourceAttr(refMembers, "groups.#", "1"),
+					),
+				},
+
+				{
+					Config: fmt.Sprintf(uaaGroupResource, "write", "custom write scope"),
+					Check: resource.ComposeTestCheckFunc(
+						testAccCheckUAAGroupExists(ref),
+						resource.TestCheckResourceAttr(ref, "display_name", "tf-acc.write"),
+						resource.TestCheckResourceAttr(ref, "description", "custom write scope"),
+						resource.TestCheckResourceAttr(refMembers, "usernames.#", "1"),
+						resource.TestCheckResourceAttr(refMembers, "groups.#", "1"),
+					),
+				},
+
+				{
+					ResourceName:      ref,
+					ImportState:       true,
+					ImportStateVerify: true,
+				},
+			},
+		})
+}
+
+func testAccCheckUAAGroupExists(resource string) resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		session := testAccProvider.Meta().(*managers.Session)
+
+		rs, ok := s.RootModule().Resources[resource]
+		if !ok {
+			return fmt.Errorf("uaa group '%s' not found in terraform state", resource)
+		}
+		group, err := getUAAGroup(session, rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		return assertSame(group.DisplayName, rs.Primary.Attributes["display_name"])
+	}
+}
+
+func testAccCheckUAAGroupDestroy() resource.TestCheckFunc {
+
+	return func(s *terraform.State) error {
+
+		session := testAccProvider.Meta().(*managers.Session)
+
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "cloudfoundry_uaa_group" {
+				continue
+			}
+			_, err := getUAAGroup(session, rs.Primary.ID)
+			if err == nil {
+				return fmt.Errorf("uaa group '%s' still exists", rs.Primary.ID)
+			}
+			if !IsErrNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -3,6 +3,7 @@ package cloudfoundry
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
@@ -12,6 +13,7 @@ import (
 	"code.cloudfoundry.org/cli/types"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/appdeployers"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/registry"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/v3appdeployers"
 )
 
@@ -98,6 +100,7 @@ func ResourceDataToAppDeploy(d *schema.ResourceData) (appdeployers.AppDeploy, er
 		ServiceBindings: bindings,
 		Mappings:        mappings,
 		Path:            d.Get("path").(string),
+		PathDownload:    pathDownloadOptions(d),
 		StartTimeout:    time.Duration(d.Get("Timeout").(int)) * time.Second,
 		BindTimeout:     DefaultBindTimeout,
 		StageTimeout:    DefaultStageTimeout,
@@ -250,6 +253,18 @@ func ResourceDataToAppDeployV3(d *schema.ResourceData) (v3appdeployers.AppDeploy
 		app.LifecycleBuildpacks = []string{bpkg.(string)}
 	}
 
+	var cnbCredentials v3appdeployers.CNBCredentials
+	if d.Get("lifecycle").(string) == string(v3appdeployers.AppLifecycleTypeCNB) {
+		app.LifecycleType = v3appdeployers.AppLifecycleTypeCNB
+		cnbCredentials = v3appdeployers.CNBCredentials{}
+		for _, c := range GetListOfStructs(d.Get("cnb_credentials")) {
+			cnbCredentials[c["registry"].(string)] = v3appdeployers.CNBCredential{
+				Username: c["username"].(string),
+				Password: c["password"].(string),
+			}
+		}
+	}
+
 	if d.Get("stopped").(bool) {
 		app.State = v3Constants.ApplicationStopped
 	}
@@ -305,7 +320,7 @@ func ResourceDataToAppDeployV3(d *schema.ResourceData) (v3appdeployers.AppDeploy
 	}
 
 	appPackage := resources.Package{
-		DockerImage:    d.Get("docker_image").(string),
+		DockerImage:    dockerPackageImage(d),
 		DockerPassword: DockerPassword,
 		DockerUsername: DockerUsername,
 	}
@@ -335,11 +350,13 @@ func ResourceDataToAppDeployV3(d *schema.ResourceData) (v3appdeployers.AppDeploy
 		Mappings:        mappings,
 		ServiceBindings: bindings,
 		Path:            d.Get("path").(string),
+		PathDownload:    pathDownloadOptions(d),
 		BindTimeout:     DefaultBindTimeout,
 		StageTimeout:    DefaultStageTimeout,
 		StartTimeout:    time.Duration(d.Get("timeout").(int)) * time.Second,
 		EnvVars:         envVars,
 		Ports:           ports,
+		CNBCredentials:  cnbCredentials,
 	}
 
 	return appDeploy, nil
@@ -352,6 +369,9 @@ func AppDeployV3ToResourceData(d *schema.ResourceData, appDeploy v3appdeployers.
 	_ = d.Set("ports", appDeploy.Ports)
 	_ = d.Set("stack", appDeploy.App.StackName)
 
+	if appDeploy.App.LifecycleType != "" {
+		_ = d.Set("lifecycle", string(appDeploy.App.LifecycleType))
+	}
 	if bpkg := appDeploy.App.LifecycleBuildpacks; len(bpkg) > 0 {
 		if _, ok := d.GetOk("buildpacks"); ok {
 			_ = d.Set("buildpacks", bpkg)
@@ -368,7 +388,9 @@ func AppDeployV3ToResourceData(d *schema.ResourceData, appDeploy v3appdeployers.
 
 	_ = d.Set("enable_ssh", appDeploy.EnableSSH.Value)
 	_ = d.Set("stopped", appDeploy.App.State == v3Constants.ApplicationStopped)
-	_ = d.Set("docker_image", appDeploy.AppPackage.DockerImage)
+	dockerImage, dockerImageDigest := dockerImageState(d.Get("docker_image").(string), d.Get("docker_image_digest").(string), appDeploy.AppPackage.DockerImage)
+	_ = d.Set("docker_image", dockerImage)
+	_ = d.Set("docker_image_digest", dockerImageDigest)
 	_ = d.Set("environment", appDeploy.EnvVars)
 	// Ensure id_bg is set
 	if idBg, ok := d.GetOk("id_bg"); !ok || idBg == "" {
@@ -470,3 +492,34 @@ func ProcessToResourceData(d *schema.ResourceData, proc resources.Process) {
 		_ = d.Set("command", proc.Command.Value)
 	}
 }
+
+// dockerPackageImage gives the docker image to deploy, pinned to the digest resolved when deploying if any
+func dockerPackageImage(d *schema.ResourceData) string {
+	image := d.Get("docker_image").(string)
+	if digest := d.Get("docker_image_digest").(string); image != "" && digest != "" {
+		return registry.Pin(image, digest)
+	}
+	return image
+}
+
+// dockerImageState gives docker_image and docker_image_digest from the image deployed and the digest the image
+// configured is resolved to. The image configured is kept when the image deployed is this one pinned to a digest,
+// it is kept pinned to the deployed digest when its tag has been moved to another image so that the app is redeployed.
+// The digest resolved is kept when the image configured is deployed unpinned, it is pinned at next deployment.
+func dockerImageState(configured string, resolvedDigest string, deployed string) (string, string) {
+	i := strings.Index(deployed, "@")
+	if i < 0 {
+		if deployed == configured {
+			return deployed, resolvedDigest
+		}
+		return deployed, ""
+	}
+	digest := deployed[i+1:]
+	if configured != "" && registry.Pin(configured, digest) == deployed {
+		if resolvedDigest != "" && resolvedDigest != digest {
+			return configured + "@" + digest, digest
+		}
+		return configured, digest
+	}
+	return deployed, digest
+}
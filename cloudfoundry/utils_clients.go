@@ -1,7 +1,10 @@
 package cloudfoundry
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
 	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
@@ -11,6 +14,7 @@ import (
 	"code.cloudfoundry.org/cli/api/uaa"
 	"code.cloudfoundry.org/cli/resources"
 	"code.cloudfoundry.org/cli/types"
+	goResource "github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
 )
@@ -136,9 +140,23 @@ func IsErrNotFound(err error) bool {
 	if uaaErr, ok := err.(uaa.RawHTTPStatusError); ok && uaaErr.StatusCode == 404 {
 		return true
 	}
+	if goResource.IsResourceNotFoundError(err) || goResource.IsNotFoundError(err) {
+		return true
+	}
 	return false
 }
 
+// IsErrForbidden returns true if the user is not allowed to access the requested resource
+func IsErrForbidden(err error) bool {
+	if httpErr, ok := err.(ccerror.RawHTTPStatusError); ok && httpErr.StatusCode == 403 {
+		return true
+	}
+	if _, ok := err.(ccerror.ForbiddenError); ok {
+		return true
+	}
+	return goResource.IsNotAuthorizedError(err)
+}
+
 type PollingConfig struct {
 	Session  *managers.Session
 	JobURL   ccv3.JobURL
@@ -178,3 +196,56 @@ func PollAsyncJob(config PollingConfig) error {
 		return false, nil
 	}, config.Interval, config.Timeout)
 }
+
+// rawJSONRequest calls cloud controller through the raw client with body encoded as json (if not nil)
+// and decodes the json response into result (if not nil), response headers are returned to get job location
+func rawJSONRequest(session *managers.Session, method string, path string, body interface{}, result interface{}) (http.Header, error) {
+	return session.RawClient.DoJSON(method, path, body, result)
+}
+
+// uaaRawJSONRequest makes a request to uaa with the admin client given in provider configuration
+func uaaRawJSONRequest(session *managers.Session, method string, path string, body interface{}, result interface{}) (http.Header, error) {
+	return uaaRawJSONRequestWithHeader(session, method, path, nil, body, result)
+}
+
+func uaaRawJSONRequestWithHeader(session *managers.Session, method string, path string, header http.Header, body interface{}, result interface{}) (http.Header, error) {
+	if session.UAARawClient == nil {
+		return nil, fmt.Errorf("uaa_client_id and uaa_client_secret must be set in provider configuration to manage uaa resources")
+	}
+	return session.UAARawClient.DoJSONWithHeader(method, path, header, body, result)
+}
+
+// rawJSONListAll walks through every page of a cloud controller v3 list endpoint,
+// resources of each page are given to appendPage to be decoded
+func rawJSONListAll(session *managers.Session, path string, query url.Values, appendPage func(resources json.RawMessage) error) error {
+	next := path
+	if len(query) > 0 {
+		next += "?" + query.Encode()
+	}
+	for next != "" {
+		var page struct {
+			Pagination struct {
+				Next struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"pagination"`
+			Resources json.RawMessage `json:"resources"`
+		}
+		_, err := rawJSONRequest(session, http.MethodGet, next, nil, &page)
+		if err != nil {
+			return err
+		}
+		if err := appendPage(page.Resources); err != nil {
+			return err
+		}
+		next = ""
+		if page.Pagination.Next.Href != "" {
+			nextURL, err := url.Parse(page.Pagination.Next.Href)
+			if err != nil {
+				return err
+			}
+			next = nextURL.RequestURI()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cloudfoundry
+
+import (
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
+)
+
+func pathSHA256Schema() *schema.Schema {
+	return &schema.Schema{
+		Type:         schema.TypeString,
+		Optional:     true,
+		ValidateFunc: validation.StringMatch(regexp.MustCompile(`^[a-fA-F0-9]{64}$`), "must be a hex encoded sha256 checksum"),
+		Description:  "Expected sha256 checksum of the zip given in path, deployment fails when it does not match",
+	}
+}
+
+func pathHeadersSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeMap,
+		Optional:    true,
+		Sensitive:   true,
+		Elem:        &schema.Schema{Type: schema.TypeString},
+		Description: "Headers sent when downloading the zip given as http url in path",
+	}
+}
+
+func pathCredentialsSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeMap,
+		Optional:         true,
+		Sensitive:        true,
+		Elem:             &schema.Schema{Type: schema.TypeString},
+		ValidateDiagFunc: validation.MapKeyMatch(regexp.MustCompile(`^(username|password|token)$`), "only username and password (basic auth) or token (bearer auth) can be given"),
+		Description:      "Credentials used to download the zip given as http url in path, either username and password for basic auth or token for bearer auth",
+	}
+}
+
+// pathDownloadOptions gives options to download the zip given in path from path_sha256, path_headers and path_credentials
+func pathDownloadOptions(d *schema.ResourceData) bits.DownloadOptions {
+	opts := bits.DownloadOptions{
+		Headers: make(map[string]string),
+		SHA256:  d.Get("path_sha256").(string),
+	}
+	for k, v := range d.Get("path_headers").(map[string]interface{}) {
+		opts.Headers[k] = v.(string)
+	}
+	creds := d.Get("path_credentials").(map[string]interface{})
+	if v, ok := creds["username"]; ok {
+		opts.Username = v.(string)
+	}
+	if v, ok := creds["password"]; ok {
+		opts.Password = v.(string)
+	}
+	if v, ok := creds["token"]; ok {
+		opts.Token = v.(string)
+	}
+	return opts
+}
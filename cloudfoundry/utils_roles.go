@@ -0,0 +1,346 @@
+package cloudfoundry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv2"
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+const (
+	roleScopeOrganization = "organization"
+	roleScopeSpace        = "space"
+
+	roleTypeOrganizationUser = "organization_user"
+)
+
+// orgRoleTypes and spaceRoleTypes map attributes of org_users and space_users to cloud controller v3 role types
+var orgRoleTypes = map[string]string{
+	"managers":         "organization_manager",
+	"billing_managers": "organization_billing_manager",
+	"auditors":         "organization_auditor",
+}
+
+var spaceRoleTypes = map[string]string{
+	"managers":   "space_manager",
+	"developers": "space_developer",
+	"auditors":   "space_auditor",
+}
+
+// roleByUsername is a role given to a user identified by its username and origin,
+// cloud controller creates the user at role creation when it only exists in uaa (e.g. federated users before first login)
+type roleByUsername struct {
+	Username string
+	Origin   string
+	Role     string
+}
+
+func usersByUsernameSchema(roleTypes map[string]string) *schema.Schema {
+	roles := make([]string, 0, len(roleTypes))
+	for r := range roleTypes {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+	return &schema.Schema{
+		Type:        schema.TypeSet,
+		Optional:    true,
+		Description: "Users given roles by their username and origin, users only need to exist in uaa",
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"username": &schema.Schema{
+					Type:     schema.TypeString,
+					Required: true,
+				},
+				"origin": &schema.Schema{
+					Type:     schema.TypeString,
+					Optional: true,
+					Default:  "uaa",
+				},
+				"roles": &schema.Schema{
+					Type:     schema.TypeSet,
+					Required: true,
+					MinItems: 1,
+					Elem: &schema.Schema{
+						Type:         schema.TypeString,
+						ValidateFunc: validation.StringInSlice(roles, false),
+					},
+					Set: schema.HashString,
+				},
+			},
+		},
+	}
+}
+
+// rolesByUsernameFromSet flattens users_by_username into one role per user and role attribute
+func rolesByUsernameFromSet(users *schema.Set) []roleByUsername {
+	roles := make([]roleByUsername, 0)
+	for _, u := range users.List() {
+		user := u.(map[string]interface{})
+		for _, r := range user["roles"].(*schema.Set).List() {
+			roles = append(roles, roleByUsername{
+				Username: user["username"].(string),
+				Origin:   user["origin"].(string),
+				Role:     r.(string),
+			})
+		}
+	}
+	return roles
+}
+
+// rolesByUsernameCustomizeDiff reports users which can't be found in uaa during plan instead of failing during apply,
+// check is skipped when uaa can't be queried
+func rolesByUsernameCustomizeDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.HasChange("users_by_username") || !d.NewValueKnown("users_by_username") {
+		return nil
+	}
+	session := meta.(*managers.Session)
+	if session.UAARawClient == nil {
+		log.Printf("[WARN] skipping check of users_by_username, uaa_client_id must be set in provider configuration to look up users in uaa")
+		return nil
+	}
+	unresolved := make([]string, 0)
+	for _, u := range d.Get("users_by_username").(*schema.Set).List() {
+		user := u.(map[string]interface{})
+		userID, err := uaaUserIDByUsername(session, user)
+		if err != nil {
+			log.Printf("[WARN] skipping check of users_by_username, users can't be looked up in uaa: %s", err.Error())
+			return nil
+		}
+		if userID == "" {
+			unresolved = append(unresolved, fmt.Sprintf("%s (origin %s)", user["username"], user["origin"]))
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("users_by_username: users must exist in uaa to be given roles, not found: %s", strings.Join(unresolved, ", "))
+	}
+	return nil
+}
+
+// readRolesByUsername keeps in users_by_username only roles users still have, it returns guids of these users
+// by attribute so they are not also reported as users given by guid
+func readRolesByUsername(session *managers.Session, d *schema.ResourceData, scope string, scopeGUID string, roleTypes map[string]string) (map[string][]string, error) {
+	managedGUIDs := make(map[string][]string)
+	users := make([]interface{}, 0)
+	for _, u := range d.Get("users_by_username").(*schema.Set).List() {
+		user := u.(map[string]interface{})
+		userGUID, err := ccUserGUIDByUsername(session, user["username"].(string), user["origin"].(string))
+		if err != nil {
+			return nil, err
+		}
+		if userGUID == "" {
+			continue
+		}
+		currentRoles, err := getUserRoleTypes(session, scope, scopeGUID, userGUID)
+		if err != nil {
+			return nil, err
+		}
+		roles := make([]interface{}, 0)
+		for _, r := range user["roles"].(*schema.Set).List() {
+			if currentRoles[roleTypes[r.(string)]] {
+				roles = append(roles, r)
+				managedGUIDs[r.(string)] = append(managedGUIDs[r.(string)], userGUID)
+			}
+		}
+		if len(roles) == 0 {
+			continue
+		}
+		users = append(users, map[string]interface{}{
+			"username": user["username"],
+			"origin":   user["origin"],
+			"roles":    schema.NewSet(schema.HashString, roles),
+		})
+	}
+	return managedGUIDs, d.Set("users_by_username", users)
+}
+
+// updateRolesByUsername applies changes of users_by_username, orgGUID is given for space roles
+// as users must be member of the org of the space
+func updateRolesByUsername(session *managers.Session, d *schema.ResourceData, scope string, scopeGUID string, roleTypes map[string]string, orgGUID string) error {
+	old, new := d.GetChange("users_by_username")
+	oldRoles := rolesByUsernameFromSet(old.(*schema.Set))
+	newRoles := rolesByUsernameFromSet(new.(*schema.Set))
+	contains := func(roles []roleByUsername, role roleByUsername) bool {
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range oldRoles {
+		if contains(newRoles, r) {
+			continue
+		}
+		err := deleteRoleByUsername(session, scope, scopeGUID, r.Username, r.Origin, roleTypes[r.Role])
+		if err != nil {
+			return err
+		}
+	}
+	for _, r := range newRoles {
+		if contains(oldRoles, r) {
+			continue
+		}
+		if orgGUID != "" {
+			err := createRoleByUsername(session, roleScopeOrganization, orgGUID, r.Username, r.Origin, roleTypeOrganizationUser)
+			if err != nil {
+				return err
+			}
+		}
+		err := createRoleByUsername(session, scope, scopeGUID, r.Username, r.Origin, roleTypes[r.Role])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteRolesByUsername(session *managers.Session, d *schema.ResourceData, scope string, scopeGUID string, roleTypes map[string]string) error {
+	for _, r := range rolesByUsernameFromSet(d.Get("users_by_username").(*schema.Set)) {
+		err := deleteRoleByUsername(session, scope, scopeGUID, r.Username, r.Origin, roleTypes[r.Role])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createRoleByUsername(session *managers.Session, scope, scopeGUID, username, origin, roleType string) error {
+	userGUID, err := ccUserGUIDByUsername(session, username, origin)
+	if err != nil {
+		return err
+	}
+	if userGUID != "" {
+		currentRoles, err := getUserRoleTypes(session, scope, scopeGUID, userGUID)
+		if err != nil {
+			return err
+		}
+		if currentRoles[roleType] {
+			return nil
+		}
+	}
+	_, err = rawJSONRequest(session, http.MethodPost, "/v3/roles", map[string]interface{}{
+		"type": roleType,
+		"relationships": map[string]interface{}{
+			"user": map[string]interface{}{
+				"data": map[string]string{
+					"username": username,
+					"origin":   origin,
+				},
+			},
+			scope: map[string]interface{}{
+				"data": map[string]string{
+					"guid": scopeGUID,
+				},
+			},
+		},
+	}, nil)
+	return err
+}
+
+func deleteRoleByUsername(session *managers.Session, scope, scopeGUID, username, origin, roleType string) error {
+	userGUID, err := ccUserGUIDByUsername(session, username, origin)
+	if err != nil || userGUID == "" {
+		return err
+	}
+	query := url.Values{
+		"types":          []string{roleType},
+		"user_guids":     []string{userGUID},
+		scope + "_guids": []string{scopeGUID},
+	}
+	var roles struct {
+		Resources []struct {
+			GUID string `json:"guid"`
+		} `json:"resources"`
+	}
+	_, err = rawJSONRequest(session, http.MethodGet, "/v3/roles?"+query.Encode(), nil, &roles)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles.Resources {
+		header, err := rawJSONRequest(session, http.MethodDelete, "/v3/roles/"+role.GUID, nil, nil)
+		if err != nil {
+			if IsErrNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if location := header.Get("Location"); location != "" {
+			err = PollAsyncJob(PollingConfig{
+				Session: session,
+				JobURL:  ccv3.JobURL(location),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func excludeUsersByGUID(users []ccv2.User, guids []string) []ccv2.User {
+	filtered := make([]ccv2.User, 0, len(users))
+	for _, u := range users {
+		excluded := false
+		for _, guid := range guids {
+			if u.GUID == guid {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
+// ccUserGUIDByUsername returns guid of the cloud controller user, empty when user has not been created in cloud controller yet
+func ccUserGUIDByUsername(session *managers.Session, username, origin string) (string, error) {
+	query := url.Values{
+		"usernames": []string{username},
+		"origins":   []string{origin},
+	}
+	var users struct {
+		Resources []struct {
+			GUID string `json:"guid"`
+		} `json:"resources"`
+	}
+	_, err := rawJSONRequest(session, http.MethodGet, "/v3/users?"+query.Encode(), nil, &users)
+	if err != nil {
+		return "", err
+	}
+	if len(users.Resources) == 0 {
+		return "", nil
+	}
+	return users.Resources[0].GUID, nil
+}
+
+func getUserRoleTypes(session *managers.Session, scope, scopeGUID, userGUID string) (map[string]bool, error) {
+	query := url.Values{
+		"user_guids":     []string{userGUID},
+		scope + "_guids": []string{scopeGUID},
+	}
+	roleTypes := make(map[string]bool)
+	var roles struct {
+		Resources []struct {
+			Type string `json:"type"`
+		} `json:"resources"`
+	}
+	_, err := rawJSONRequest(session, http.MethodGet, "/v3/roles?"+query.Encode(), nil, &roles)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles.Resources {
+		roleTypes[role.Type] = true
+	}
+	return roleTypes, nil
+}
@@ -0,0 +1,145 @@
+package cloudfoundry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccerror"
+	"code.cloudfoundry.org/cli/api/cloudcontroller/ccv3"
+	"code.cloudfoundry.org/cli/api/router"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+// reservablePortsParse parses router group reservable ports (e.g. "1024-1033,2000")
+// and returns every port it contains
+func reservablePortsParse(reservablePorts string) ([]int, error) {
+	ports := make([]int, 0)
+	for _, portRange := range strings.Split(reservablePorts, ",") {
+		portRange = strings.TrimSpace(portRange)
+		if portRange == "" {
+			continue
+		}
+		start, end, err := portRangeParse(portRange)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid reservable ports '%s': %s", reservablePorts, err.Error())
+		}
+		if start > end {
+			return nil, fmt.Errorf("Invalid reservable ports '%s': range %s is reversed", reservablePorts, portRange)
+		}
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// getRouterGroupByGUID retrieves a router group from the routing api by its guid,
+// RouterClient is only able to look up router groups by name
+func getRouterGroupByGUID(session *managers.Session, guid string) (router.RouterGroup, error) {
+	req, err := session.RoutingRawClient.NewRequest(http.MethodGet, "/v1/router_groups", nil)
+	if err != nil {
+		return router.RouterGroup{}, err
+	}
+	resp, err := session.RoutingRawClient.Do(req)
+	if err != nil {
+		return router.RouterGroup{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return router.RouterGroup{}, ccerror.RawHTTPStatusError{
+			StatusCode: resp.StatusCode,
+		}
+	}
+
+	var routerGroups []router.RouterGroup
+	err = json.NewDecoder(resp.Body).Decode(&routerGroups)
+	if err != nil {
+		return router.RouterGroup{}, err
+	}
+	for _, routerGroup := range routerGroups {
+		if routerGroup.GUID == guid {
+			return routerGroup, nil
+		}
+	}
+	return router.RouterGroup{}, NotFound
+}
+
+// routerGroupUsedPorts returns ports already used by routes on any domain of the router group
+func routerGroupUsedPorts(session *managers.Session, routerGroupGUID string) (map[int]bool, error) {
+	used := make(map[int]bool)
+
+	domains, _, err := session.ClientV3.GetDomains()
+	if err != nil {
+		return used, err
+	}
+	domainGUIDs := make([]string, 0)
+	for _, domain := range domains {
+		if domain.RouterGroup == routerGroupGUID {
+			domainGUIDs = append(domainGUIDs, domain.GUID)
+		}
+	}
+	if len(domainGUIDs) == 0 {
+		return used, nil
+	}
+
+	routes, _, err := session.ClientV3.GetRoutes(ccv3.Query{
+		Key:    ccv3.QueryKey("domain_guids"),
+		Values: domainGUIDs,
+	})
+	if err != nil {
+		return used, err
+	}
+	for _, route := range routes {
+		if route.Port > 0 {
+			used[route.Port] = true
+		}
+	}
+	return used, nil
+}
+
+// routerGroupFreePorts returns sorted reservable ports of the router group which are not used by any route
+func routerGroupFreePorts(session *managers.Session, routerGroup router.RouterGroup) ([]int, error) {
+	reservable, err := reservablePortsParse(routerGroup.ReservablePorts)
+	if err != nil {
+		return nil, err
+	}
+	used, err := routerGroupUsedPorts(session, routerGroup.GUID)
+	if err != nil {
+		return nil, err
+	}
+	free := make([]int, 0, len(reservable))
+	for _, port := range reservable {
+		if !used[port] {
+			free = append(free, port)
+		}
+	}
+	sort.Ints(free)
+	return free, nil
+}
+
+// randomFreePort picks a free port on the router group of the given domain
+func randomFreePort(session *managers.Session, domainGUID string) (int, error) {
+	domain, _, err := session.ClientV3.GetDomain(domainGUID)
+	if err != nil {
+		return 0, err
+	}
+	if domain.RouterGroup == "" {
+		return 0, fmt.Errorf("Domain '%s' is not a tcp domain, random_port can only be used with a domain having a router group", domain.Name)
+	}
+	routerGroup, err := getRouterGroupByGUID(session, domain.RouterGroup)
+	if err != nil {
+		return 0, err
+	}
+	free, err := routerGroupFreePorts(session, routerGroup)
+	if err != nil {
+		return 0, err
+	}
+	if len(free) == 0 {
+		return 0, fmt.Errorf("No free port left in reservable ports '%s' of router group '%s'", routerGroup.ReservablePorts, routerGroup.Name)
+	}
+	return free[rand.Intn(len(free))], nil
+}
@@ -0,0 +1,92 @@
+package cloudfoundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/common"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers"
+)
+
+type servicePlanSchemaType int
+
+const (
+	servicePlanSchemaInstanceCreate servicePlanSchemaType = iota
+	servicePlanSchemaInstanceUpdate
+	servicePlanSchemaBindingCreate
+)
+
+// servicePlanParametersSchema returns the json schema declared by a plan for the given operation,
+// nil is returned when plan does not declare one
+func servicePlanParametersSchema(ctx context.Context, session *managers.Session, planGUID string, schemaType servicePlanSchemaType) (*json.RawMessage, error) {
+	plan, err := session.ClientGo.ServicePlans.Get(ctx, planGUID)
+	if err != nil {
+		return nil, err
+	}
+	switch schemaType {
+	case servicePlanSchemaInstanceUpdate:
+		return plan.Schemas.ServiceInstance.Update.Parameters, nil
+	case servicePlanSchemaBindingCreate:
+		return plan.Schemas.ServiceBinding.Create.Parameters, nil
+	}
+	return plan.Schemas.ServiceInstance.Create.Parameters, nil
+}
+
+// serviceInstancePlanGUID returns plan guid of a managed service instance, empty for user provided service instance
+func serviceInstancePlanGUID(ctx context.Context, session *managers.Session, serviceInstanceGUID string) (string, error) {
+	si, err := session.ClientGo.ServiceInstances.Get(ctx, serviceInstanceGUID)
+	if err != nil {
+		return "", err
+	}
+	if si.Type != "managed" || si.Relationships.ServicePlan == nil || si.Relationships.ServicePlan.Data == nil {
+		return "", nil
+	}
+	return si.Relationships.ServicePlan.Data.GUID, nil
+}
+
+// validateServiceParams validates parameters against the json schema declared by the plan for the operation,
+// validation is skipped when schema can't be retrieved to not prevent planning when cloud controller is not reachable
+func validateServiceParams(ctx context.Context, session *managers.Session, planGUID string, schemaType servicePlanSchemaType, attr string, params interface{}) error {
+	rawSchema, err := servicePlanParametersSchema(ctx, session, planGUID, schemaType)
+	if err != nil {
+		log.Printf("[WARN] skipping validation of %s, schema of plan '%s' can't be retrieved: %s", attr, planGUID, err.Error())
+		return nil
+	}
+	if rawSchema == nil {
+		return nil
+	}
+	var planSchema interface{}
+	if err := json.Unmarshal(*rawSchema, &planSchema); err != nil {
+		log.Printf("[WARN] skipping validation of %s, schema of plan '%s' is not valid json: %s", attr, planGUID, err.Error())
+		return nil
+	}
+	if m, ok := planSchema.(map[string]interface{}); planSchema == nil || (ok && len(m) == 0) {
+		return nil
+	}
+
+	errs := common.ValidateJSONSchema(planSchema, params)
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = "  - " + err.Error()
+	}
+	return fmt.Errorf("%s does not match the schema of service plan '%s':\n%s", attr, planGUID, strings.Join(msgs, "\n"))
+}
+
+// serviceParamsFromConfig decodes parameters given either as a map or as json,
+// ok is false when no parameters are given
+func serviceParamsFromConfig(paramsMap map[string]interface{}, paramsJSON string) (params interface{}, ok bool, err error) {
+	if len(paramsMap) > 0 {
+		return paramsMap, true, nil
+	}
+	if strings.TrimSpace(paramsJSON) == "" {
+		return nil, false, nil
+	}
+	err = json.Unmarshal([]byte(paramsJSON), &params)
+	return params, err == nil, err
+}
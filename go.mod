@@ -46,6 +46,7 @@ require (
 	github.com/cloudfoundry/go-cfclient/v3 v3.0.0-alpha.9
 	github.com/google/uuid v1.3.1
 	github.com/hashicorp/terraform-plugin-log v0.9.0
+	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
 )
 
 require (
@@ -2,9 +2,11 @@ package main
 
 import (
 	"flag"
+	"log"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
 	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry"
+	"github.com/terraform-providers/terraform-provider-cloudfoundry/cloudfoundry/managers/bits"
 )
 
 func main() {
@@ -21,4 +23,9 @@ func main() {
 		opts.ProviderAddr = "registry.terraform.io/cloudfoundry-community/cloudfoundry"
 	}
 	plugin.Serve(opts)
+
+	// zips downloaded during the run are only kept while the provider is served
+	if err := bits.CleanDownloads(); err != nil {
+		log.Printf("[WARN] downloaded zips can't be removed: %s", err.Error())
+	}
 }